@@ -26,4 +26,26 @@ var (
 	ErrBadRight              = errors.New("bad right")
 	ErrWrongMemberCount      = errors.New("wrong member count")
 	ErrMissingMember         = errors.New("member missing")
+	ErrOrderingInvalid       = errors.New("invalid queue ordering")
+	ErrPaginationInvalid     = errors.New("invalid offset or limit")
+	ErrThresholdInvalid      = errors.New("invalid downvote threshold")
+	ErrSkipPercentInvalid    = errors.New("invalid skip percentage")
+	ErrPermissionMissing     = errors.New("missing permission")
+	ErrRoleInvalid           = errors.New("invalid role")
+	ErrBanned                = errors.New("banned from JamSession")
+	ErrTooManyAttempts       = errors.New("too many failed attempts")
+	ErrPasswordInvalid       = errors.New("invalid password")
+	ErrInviteInvalid         = errors.New("invalid invite")
+	ErrExpiryInvalid         = errors.New("invalid expiry")
+	ErrQRSizeInvalid         = errors.New("invalid QR code size")
+	ErrLobbyMissing          = errors.New("missing lobby")
+	ErrLobbyMalformed        = errors.New("malformed lobby")
+	ErrNotWaiting            = errors.New("not waiting for approval")
+	ErrQuotaInvalid          = errors.New("invalid quota")
+	ErrFilterInvalid         = errors.New("invalid filter")
+	ErrReplayInvalid         = errors.New("invalid replay rule")
+	ErrFallbackInvalid       = errors.New("invalid fallback source")
+	ErrLeadTimeInvalid       = errors.New("invalid lead time")
+	ErrResumeInvalid         = errors.New("invalid stream or sequence number")
+	ErrHostChange            = errors.New("host can only be transferred with PUT /api/v1/jam/host")
 )
@@ -2,8 +2,9 @@ package server
 
 import (
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	"net"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/gorilla/sessions"
 	pkgsessions "github.com/jamfactoryapp/jamfactory-backend/api/sessions"
@@ -34,10 +35,34 @@ func (s *Server) CurrentJamSession(r *http.Request) *jamsession.JamSession {
 		// Panic because JamSession middleware is missing
 		panic(err)
 	}
-	jamSession.Timestamp = time.Now()
+	jamSession.Touch()
 	return jamSession
 }
 
+// CurrentMember returns the member of the current user in the current JamSession
+func (s *Server) CurrentMember(r *http.Request) (*jamsession.Member, error) {
+	user := s.CurrentUser(r)
+	jamSession := s.CurrentJamSession(r)
+	members, err := jamSession.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	return members.Get(user.Identifier)
+}
+
+// CurrentHost returns the user hosting the current JamSession, whose Spotify account plays the songs
+func (s *Server) CurrentHost(r *http.Request) (*users.User, error) {
+	members, err := s.CurrentJamSession(r).GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	hostMember, err := members.Host()
+	if err != nil {
+		return nil, err
+	}
+	return s.users.GetUserByIdentifier(r.Context(), hostMember.Identifier)
+}
+
 func (s *Server) CurrentIdentifier(r *http.Request) string {
 	session := s.CurrentSession(r)
 	id, err := pkgsessions.Identifier(session)
@@ -51,3 +76,19 @@ func (s *Server) CurrentVoteID(r *http.Request) string {
 	user := s.CurrentUser(r)
 	return user.Identifier
 }
+
+// CurrentIP returns the address of the client. Behind a trusted reverse proxy, it is the address the proxy
+// appended to the X-Forwarded-For header.
+func (s *Server) CurrentIP(r *http.Request) string {
+	if s.config.TrustProxy {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
@@ -3,7 +3,10 @@ package server
 import (
 	"github.com/jamfactoryapp/jamfactory-backend/internal/logutils"
 	log "github.com/sirupsen/logrus"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func (s *Server) error(w http.ResponseWriter, err error, code int, level log.Level) {
@@ -27,6 +30,13 @@ func (s *Server) errNotFound(w http.ResponseWriter, err error, level log.Level)
 	s.error(w, err, http.StatusNotFound, level)
 }
 
+func (s *Server) errTooManyRequests(w http.ResponseWriter, err error, retryAfter time.Duration, level log.Level) {
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+	s.error(w, err, http.StatusTooManyRequests, level)
+}
+
 func (s *Server) errInternalServerError(w http.ResponseWriter, err error, level log.Level) {
 	s.error(w, err, http.StatusInternalServerError, level)
 }
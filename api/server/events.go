@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eventHeartbeat = 15 * time.Second
+	// eventRetry is the time an EventSource waits before reconnecting
+	eventRetry     = 3 * time.Second
+	eventWriteWait = 10 * time.Second
+)
+
+var errStreamingUnsupported = errors.New("streaming unsupported")
+
+// jamEvents streams the notifications of the JamSession joined by the user as server-sent events. A reconnecting
+// client passes the ID of the last event it received as Last-Event-ID to receive the events it missed.
+func (s *Server) jamEvents(w http.ResponseWriter, r *http.Request) {
+	jamSession := s.CurrentJamSession(r)
+
+	var resume *notifications.Resume
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		resume = notifications.ParseEventID(id)
+	}
+
+	stream, err := openEventStream(w, r)
+	if err != nil {
+		s.errInternalServerError(w, err, log.WarnLevel)
+		return
+	}
+	defer stream.close()
+
+	client := jamSession.IntroduceStreamClient(s.CurrentUser(r).Identifier, resume)
+	defer client.Done()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	if err := stream.write(func(w io.Writer) error {
+		_, err := fmt.Fprintf(w, "retry: %d\n\n", eventRetry.Milliseconds())
+		return err
+	}); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-stream.done:
+			return
+		case msg, ok := <-client.Send:
+			// The JamSession was closed or the client couldn't keep up. In the latter case it reconnects and resumes
+			if !ok {
+				if client.Lagging() {
+					log.WithField("Identifier", client.Identifier).Debug("Closing lagging event stream")
+				}
+				return
+			}
+			if err := stream.write(func(w io.Writer) error { return client.WriteEvent(w, msg) }); err != nil {
+				log.Trace("Error writing event: ", err)
+				return
+			}
+		case <-heartbeat.C:
+			if err := stream.write(func(w io.Writer) error {
+				_, err := io.WriteString(w, ": heartbeat\n\n")
+				return err
+			}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventStream is the connection of a server-sent events stream. HTTP/1 connections are taken over from the server,
+// like websocket connections, so the stream isn't ended by the write timeout of the server. Other connections end
+// shortly before the write timeout and the client reconnects. done is closed, once the stream has to end.
+type eventStream struct {
+	writer *bufio.Writer
+	conn   net.Conn
+	flush  func()
+	done   chan struct{}
+}
+
+func openEventStream(w http.ResponseWriter, r *http.Request) (*eventStream, error) {
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	// Keeps reverse proxies from buffering the events
+	header.Set("X-Accel-Buffering", "no")
+
+	if hijacker, ok := w.(http.Hijacker); ok && r.ProtoMajor == 1 {
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			return nil, err
+		}
+		// Clear the deadlines set by the server for the request
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			log.Trace("Error clearing deadline: ", err)
+		}
+		header.Set("Connection", "close")
+		if _, err := fmt.Fprintf(rw, "HTTP/1.1 %d %s\r\n", http.StatusOK, http.StatusText(http.StatusOK)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		if err := header.Write(rw); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		if _, err := rw.WriteString("\r\n"); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		stream := &eventStream{writer: rw.Writer, conn: conn, done: make(chan struct{})}
+		// Clients don't send anything, so reading only ends once the connection is gone
+		go func() {
+			_, _ = io.Copy(io.Discard, rw.Reader)
+			close(stream.done)
+		}()
+		return stream, nil
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errStreamingUnsupported
+	}
+	w.WriteHeader(http.StatusOK)
+	stream := &eventStream{writer: bufio.NewWriter(w), flush: flusher.Flush, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(writeTimeout - time.Second):
+		}
+		close(stream.done)
+	}()
+	return stream, nil
+}
+
+// write writes a single event with fn and sends it to the client right away
+func (s *eventStream) write(fn func(w io.Writer) error) error {
+	if s.conn != nil {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(eventWriteWait)); err != nil {
+			return err
+		}
+	}
+	if err := fn(s.writer); err != nil {
+		return err
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if s.flush != nil {
+		s.flush()
+	}
+	return nil
+}
+
+func (s *eventStream) close() {
+	if s.conn != nil {
+		if err := s.conn.Close(); err != nil {
+			log.Trace("Error closing connection: ", err)
+		}
+	}
+}
@@ -5,11 +5,17 @@ import (
 	"crypto/sha1"
 	"encoding/base32"
 	"encoding/hex"
+	"errors"
 	"net/http"
+	"net/url"
+	"path"
+	"time"
 
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/ratelimit"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
-	"github.com/zmb3/spotify/v2"
 
 	apierrors "github.com/jamfactoryapp/jamfactory-backend/api/errors"
 	"github.com/jamfactoryapp/jamfactory-backend/api/sessions"
@@ -18,28 +24,33 @@ import (
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
 	log "github.com/sirupsen/logrus"
+	"github.com/skip2/go-qrcode"
+)
+
+var (
+	// joinIPPolicy limits the failed attempts to join any JamSession from a single client address
+	joinIPPolicy = ratelimit.Policy{Attempts: 10, Window: 10 * time.Minute, Lockout: 15 * time.Minute}
+	// joinLabelPolicy limits the failed attempts to join a single JamSession from all clients. The lockout is
+	// shorter, as it also locks out the legitimate guests of the JamSession.
+	joinLabelPolicy = ratelimit.Policy{Attempts: 30, Window: 10 * time.Minute, Lockout: 5 * time.Minute}
+	// joinBannedPolicy locks a client address out of a single JamSession after an attempt to join it as banned user.
+	// Guests are identified by their session cookie, so a banned guest could join again with a new cookie otherwise.
+	joinBannedPolicy = ratelimit.Policy{Attempts: 1, Window: time.Hour, Lockout: time.Hour}
+)
+
+const (
+	// inviteTokenName separates invite tokens from the session cookies signed with the same key pairs
+	inviteTokenName = "invite"
+	// invitePath is the path of the client, which joins a JamSession with the invite in the query parameter invite
+	invitePath      = "/join"
+	inviteTTL       = 24 * time.Hour
+	inviteQRSize    = 256
+	inviteQRSizeMin = 64
+	inviteQRSizeMax = 1024
 )
 
 func (s *Server) getMemberResponse(ctx context.Context, members jamsession.Members) types.GetJamMembersResponse {
-	memberResponse := make([]types.JamMember, 0)
-	for _, member := range members {
-		user, err := s.users.GetUserByIdentifier(ctx, member.GetIdentifier())
-		if err != nil {
-			log.Warn("User for identifier not found", member.GetIdentifier())
-			continue
-		}
-		userInfo, err := user.GetInfo()
-		if err != nil {
-			log.Warn("UserInfo for identifier not found", member.GetIdentifier())
-			continue
-		}
-		memberResponse = append(memberResponse, types.JamMember{
-			DisplayName: userInfo.UserName,
-			Identifier:  user.Identifier,
-			Permissions: member.GetPermissions(),
-		})
-	}
-	return types.GetJamMembersResponse{Members: memberResponse}
+	return members.Response(ctx, s.users)
 }
 
 func (s *Server) getMembers(w http.ResponseWriter, r *http.Request) {
@@ -59,55 +70,81 @@ func (s *Server) setMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jamSession := s.CurrentJamSession(r)
-	members, err := jamSession.GetMembers()
-	if err != nil {
-		s.errInternalServerError(w, err, log.DebugLevel)
-		return
-	}
 	// Validate Request
-	if len(body.Members) != len(*members) {
-		s.errBadRequest(w, apierrors.ErrWrongMemberCount, log.DebugLevel)
-		return
-	}
 	hostCount := 0
-	for _, requestMember := range body.Members {
-		if !requestMember.Permissions.Valid() {
+	for i, requestMember := range body.Members {
+		if requestMember.Role != "" {
+			if !requestMember.Role.Valid() {
+				s.errBadRequest(w, apierrors.ErrRoleInvalid, log.DebugLevel)
+				return
+			}
+			// The permissions of the role are extended by the listed permissions
+			body.Members[i].Permissions = append(requestMember.Role.Permissions(), requestMember.Permissions...)
+		}
+		if !body.Members[i].Permissions.Valid() {
 			s.errBadRequest(w, apierrors.ErrBadRight, log.DebugLevel)
 			return
 		}
-
-		member := jamsession.NewMember(requestMember.Identifier, requestMember.Permissions...)
-		if member.HasPermissions(permissions.Host) {
+		if body.Members[i].Permissions.Grants(permissions.Host) {
 			hostCount++
 		}
-
-		included := false
-		for _, availableMembers := range *members {
-			if requestMember.Identifier == availableMembers.GetIdentifier() {
-				included = true
-				break
-			}
-		}
-		if !included {
-			s.errBadRequest(w, apierrors.ErrMissingMember, log.DebugLevel)
-			return
-		}
 	}
 	if hostCount != 1 {
 		s.errBadRequest(w, apierrors.ErrOnlyOneHost, log.DebugLevel)
 		return
 	}
-	// Request is valid. Apply changes
-	for _, availableMembers := range *members {
+
+	user := s.CurrentUser(r)
+	jamSession := s.CurrentJamSession(r)
+	var members *jamsession.Members
+	err := jamSession.UpdateMembers(func(current *jamsession.Members) error {
+		if len(body.Members) != len(*current) {
+			return apierrors.ErrWrongMemberCount
+		}
 		for _, requestMember := range body.Members {
-			if requestMember.Identifier == availableMembers.GetIdentifier() {
-				availableMembers.SetPermissions(requestMember.Permissions...)
+			if _, err := current.Get(requestMember.Identifier); err != nil {
+				return apierrors.ErrMissingMember
 			}
 		}
-	}
-
-	if err := jamSession.SetMembers(members); err != nil {
+		requester, err := current.Get(user.Identifier)
+		if err != nil {
+			return apierrors.ErrMissingMember
+		}
+		// The host is transferred by setHost, which hands over the playback
+		for _, requestMember := range body.Members {
+			member, _ := current.Get(requestMember.Identifier)
+			if member.GetPermissions().Grants(permissions.Host) != requestMember.Permissions.Grants(permissions.Host) {
+				return apierrors.ErrHostChange
+			}
+		}
+		// Members can only change members, whose permissions they were granted themselves,
+		// and only grant their own permissions
+		for _, requestMember := range body.Members {
+			member, _ := current.Get(requestMember.Identifier)
+			if member.GetPermissions().Equal(requestMember.Permissions) {
+				continue
+			}
+			if !requester.Can(member.GetPermissions()...) || !requester.Can(requestMember.Permissions...) {
+				return apierrors.ErrPermissionMissing
+			}
+		}
+		// Request is valid. Apply changes
+		for _, requestMember := range body.Members {
+			member, _ := current.Get(requestMember.Identifier)
+			member.SetPermissions(requestMember.Permissions...)
+		}
+		members = current
+		return nil
+	})
+	switch err {
+	case nil:
+	case apierrors.ErrWrongMemberCount, apierrors.ErrMissingMember, apierrors.ErrHostChange:
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	case apierrors.ErrPermissionMissing:
+		s.errForbidden(w, err, log.DebugLevel)
+		return
+	default:
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
 	}
@@ -127,11 +164,7 @@ func (s *Server) getJamSession(w http.ResponseWriter, r *http.Request) {
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
 	}
-	utils.EncodeJSONBody(w, types.GetJamResponse{
-		Label:  jamSession.JamLabel,
-		Name:   settings.Name,
-		Active: settings.Active,
-	})
+	utils.EncodeJSONBody(w, types.GetJamResponse(settings.Response(jamSession.JamLabel)))
 }
 
 func (s *Server) setJamSession(w http.ResponseWriter, r *http.Request) {
@@ -141,6 +174,80 @@ func (s *Server) setJamSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ordering := queue.Ordering(body.Ordering.Value)
+	if body.Ordering.Set && body.Ordering.Valid && !ordering.Valid() {
+		s.errBadRequest(w, apierrors.ErrOrderingInvalid, log.DebugLevel)
+		return
+	}
+	if body.DownvoteThreshold.Set && body.DownvoteThreshold.Valid && body.DownvoteThreshold.Value < 0 {
+		s.errBadRequest(w, apierrors.ErrThresholdInvalid, log.DebugLevel)
+		return
+	}
+	if body.SkipPercentage.Set && body.SkipPercentage.Valid &&
+		(body.SkipPercentage.Value < 1 || body.SkipPercentage.Value > 100) {
+		s.errBadRequest(w, apierrors.ErrSkipPercentInvalid, log.DebugLevel)
+		return
+	}
+	for _, limit := range []types.JSONInt{body.MaxPending, body.MaxAdditions} {
+		if limit.Set && limit.Valid && limit.Value < 0 {
+			s.errBadRequest(w, apierrors.ErrQuotaInvalid, log.DebugLevel)
+			return
+		}
+	}
+	for _, duration := range []types.JSONInt{body.AdditionWindow, body.AdditionCooldown} {
+		if duration.Set && duration.Valid &&
+			(duration.Value < 0 || time.Duration(duration.Value)*time.Second > queue.AdditionRetention) {
+			s.errBadRequest(w, apierrors.ErrQuotaInvalid, log.DebugLevel)
+			return
+		}
+	}
+	if body.ReplayCooldown.Set && body.ReplayCooldown.Valid &&
+		(body.ReplayCooldown.Value < 0 || time.Duration(body.ReplayCooldown.Value)*time.Second > queue.MaxReplayCooldown) {
+		s.errBadRequest(w, apierrors.ErrReplayInvalid, log.DebugLevel)
+		return
+	}
+	if body.ReplaySongs.Set && body.ReplaySongs.Valid &&
+		(body.ReplaySongs.Value < 0 || body.ReplaySongs.Value > queue.HistoryRetention) {
+		s.errBadRequest(w, apierrors.ErrReplayInvalid, log.DebugLevel)
+		return
+	}
+	leadTime := time.Duration(body.LeadTime.Value) * time.Millisecond
+	if body.LeadTime.Set && body.LeadTime.Valid && (leadTime < jamsession.MinLeadTime || leadTime > jamsession.MaxLeadTime) {
+		s.errBadRequest(w, apierrors.ErrLeadTimeInvalid, log.DebugLevel)
+		return
+	}
+	var filter jamsession.Filter
+	if body.Filter != nil {
+		filter = jamsession.NewFilter(*body.Filter)
+		if !filter.Valid() {
+			s.errBadRequest(w, apierrors.ErrFilterInvalid, log.DebugLevel)
+			return
+		}
+	}
+	var fallback jamsession.Fallback
+	if body.Fallback != nil {
+		fallback = jamsession.NewFallback(*body.Fallback)
+		if !fallback.Valid() {
+			s.errBadRequest(w, apierrors.ErrFallbackInvalid, log.DebugLevel)
+			return
+		}
+	}
+	// Hash the password once, as UpdateSettings may apply the changes more than once
+	var passwordHash string
+	if body.Password.Set && body.Password.Valid {
+		var err error
+		passwordHash, err = jamsession.HashPassword(body.Password.Value)
+		switch err {
+		case nil:
+		case jamsession.ErrPasswordTooLong:
+			s.errBadRequest(w, apierrors.ErrPasswordInvalid, log.DebugLevel)
+			return
+		default:
+			s.errInternalServerError(w, err, log.DebugLevel)
+			return
+		}
+	}
+
 	jamSession := s.CurrentJamSession(r)
 	settings, err := jamSession.GetSettings()
 	if err != nil {
@@ -152,11 +259,6 @@ func (s *Server) setJamSession(w http.ResponseWriter, r *http.Request) {
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
 	}
-	currentQueue, err := jamSession.GetQueue()
-	if err != nil {
-		s.errInternalServerError(w, err, log.DebugLevel)
-		return
-	}
 	hostMember, err := members.Host()
 	if err != nil {
 		s.errInternalServerError(w, err, log.DebugLevel)
@@ -168,51 +270,109 @@ func (s *Server) setJamSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if body.Active.Set && body.Active.Valid {
-		if body.Active.Value && !settings.Active {
-			song, err := currentQueue.GetNext()
-			if err != nil {
-				s.errBadRequest(w, apierrors.ErrQueueEmpty, log.DebugLevel)
-				return
-			}
-			if host.GetPlayerState().Device.ID == "" {
-				s.errBadRequest(w, apierrors.ErrNoDevice, log.DebugLevel)
+	// Start the playback, before the JamSession is saved as active. A new fallback source is already used for it.
+	fallbackReset := false
+	if body.Active.Set && body.Active.Valid && body.Active.Value && !settings.Active {
+		if host.GetPlayerState().Device.ID == "" {
+			s.errBadRequest(w, apierrors.ErrNoDevice, log.DebugLevel)
+			return
+		}
+		start := *settings
+		if body.Fallback != nil && fallback != settings.Fallback {
+			start.Fallback = fallback
+			if err := jamSession.ResetFallback(); err != nil {
+				s.errInternalServerError(w, err, log.DebugLevel)
 				return
 			}
-
-			// TODO: jamSession.Play or user.Play ???
-			jamSession.Play(r.Context(), song.Track, true)
-			jamSession.SocketQueueUpdate()
+			fallbackReset = true
+		}
+		switch err := jamSession.Start(r.Context(), &start, host); {
+		case errors.Is(err, queue.ErrQueueEmpty):
+			s.errBadRequest(w, apierrors.ErrQueueEmpty, log.DebugLevel)
+			return
+		case err != nil:
+			s.errInternalServerError(w, err, log.DebugLevel)
+			return
 		}
-		settings.Active = body.Active.Value
 	}
 
-	if body.Name.Set && body.Name.Valid {
-		settings.Name = body.Name.Value
+	err = jamSession.UpdateSettings(func(current *jamsession.Settings) error {
+		if body.Active.Set && body.Active.Valid {
+			current.Active = body.Active.Value
+		}
+		if body.Name.Set && body.Name.Valid {
+			current.Name = body.Name.Value
+		}
+		if body.Password.Set && body.Password.Valid {
+			current.Password = passwordHash
+		}
+		if body.Ordering.Set && body.Ordering.Valid {
+			current.Ordering = ordering
+		}
+		if body.DownvoteThreshold.Set && body.DownvoteThreshold.Valid {
+			current.DownvoteThreshold = body.DownvoteThreshold.Value
+		}
+		if body.SkipPercentage.Set && body.SkipPercentage.Valid {
+			current.SkipPercentage = body.SkipPercentage.Value
+		}
+		if body.AutoHandover.Set && body.AutoHandover.Valid {
+			current.AutoHandover = body.AutoHandover.Value
+		}
+		if body.RequireApproval.Set && body.RequireApproval.Valid {
+			current.RequireApproval = body.RequireApproval.Value
+		}
+		if body.MaxPending.Set && body.MaxPending.Valid {
+			current.Quota.MaxPending = body.MaxPending.Value
+		}
+		if body.MaxAdditions.Set && body.MaxAdditions.Valid {
+			current.Quota.MaxAdditions = body.MaxAdditions.Value
+		}
+		if body.AdditionWindow.Set && body.AdditionWindow.Valid {
+			current.Quota.Window = time.Duration(body.AdditionWindow.Value) * time.Second
+		}
+		if body.AdditionCooldown.Set && body.AdditionCooldown.Valid {
+			current.Quota.Cooldown = time.Duration(body.AdditionCooldown.Value) * time.Second
+		}
+		if body.ReplayCooldown.Set && body.ReplayCooldown.Valid {
+			current.Replay.Cooldown = time.Duration(body.ReplayCooldown.Value) * time.Second
+		}
+		if body.ReplaySongs.Set && body.ReplaySongs.Valid {
+			current.Replay.Songs = body.ReplaySongs.Value
+		}
+		if body.LeadTime.Set && body.LeadTime.Valid {
+			current.LeadTime = leadTime
+		}
+		if body.Filter != nil {
+			current.Filter = filter
+		}
+		if body.Fallback != nil {
+			current.Fallback = fallback
+		}
+		settings = current
+		return nil
+	})
+	if err != nil {
+		s.errInternalServerError(w, apierrors.ErrMissingMember, log.WarnLevel)
+		return
 	}
 
-	if body.Password.Set && body.Password.Valid {
-		settings.Password = body.Password.Value
+	if (body.Ordering.Set && body.Ordering.Valid) || (body.DownvoteThreshold.Set && body.DownvoteThreshold.Valid) {
+		// Apply the new ordering and downvote threshold to the queue
+		if err := jamSession.UpdateQueue(func(q *queue.Queue) error { return nil }); err != nil {
+			s.errInternalServerError(w, err, log.DebugLevel)
+			return
+		}
+		jamSession.SocketQueueUpdate()
 	}
-
-	if err := jamSession.SetSettings(settings); err != nil {
-		s.errInternalServerError(w, apierrors.ErrMissingMember, log.WarnLevel)
-		return
+	if body.Fallback != nil && !fallbackReset {
+		if err := jamSession.ResetFallback(); err != nil {
+			s.errInternalServerError(w, err, log.DebugLevel)
+			return
+		}
 	}
 
-	jamSession.NotifyClients(&notifications.Message{
-		Event: notifications.Jam,
-		Message: types.SocketJamMessage{
-			Label:  jamSession.JamLabel,
-			Name:   settings.Name,
-			Active: settings.Active,
-		},
-	})
-	utils.EncodeJSONBody(w, types.GetJamResponse{
-		Label:  jamSession.JamLabel,
-		Name:   settings.Name,
-		Active: settings.Active,
-	})
+	jamSession.SocketJamUpdate()
+	utils.EncodeJSONBody(w, types.GetJamResponse(settings.Response(jamSession.JamLabel)))
 }
 
 func (s *Server) getPlayback(w http.ResponseWriter, r *http.Request) {
@@ -359,9 +519,34 @@ func (s *Server) joinJamSession(w http.ResponseWriter, r *http.Request) {
 
 	session := s.CurrentSession(r)
 	jamLabel := body.Label
+	ip := s.CurrentIP(r)
+
+	// Check the lockout first, so locked out clients can't keep guessing
+	if s.joinLocked(w, joinIPKey(ip)) {
+		return
+	}
+	var invite *inviteToken
+	if body.Token != "" {
+		// Invites can't be guessed, so they aren't affected by the lockout of the label
+		invite = &inviteToken{}
+		if err := s.store.DecodeToken(inviteTokenName, body.Token, invite); err != nil {
+			s.joinFailed(ip, "", joinInvalidInvite)
+			s.errUnauthorized(w, apierrors.ErrInviteInvalid, log.DebugLevel)
+			return
+		}
+		jamLabel = invite.Label
+	} else if s.joinLocked(w, joinLabelKey(jamLabel)) {
+		return
+	}
+	if s.joinLocked(w, joinBannedKey(jamLabel, ip)) {
+		return
+	}
 
 	jamSession, err := s.jamFactory.GetJamSessionByLabel(jamLabel)
 	if err != nil {
+		if errors.Is(err, jamsession.ErrJamSessionMissing) {
+			s.joinFailed(ip, jamLabel, joinUnknownLabel)
+		}
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
 	}
@@ -376,8 +561,9 @@ func (s *Server) joinJamSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if the password is correct
-	if body.Password != settings.Password {
+	// Check if the password is correct. Invites replace the password
+	if invite == nil && !settings.CheckPassword(body.Password) {
+		s.joinFailed(ip, jamLabel, joinWrongPassword)
 		s.errUnauthorized(w, apierrors.ErrWrongPassword, log.DebugLevel)
 		return
 	}
@@ -396,17 +582,75 @@ func (s *Server) joinJamSession(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if settings.Banned(user.Identifier) {
+		s.joinFailed(ip, jamLabel, joinBanned)
+		s.errForbidden(w, apierrors.ErrBanned, log.DebugLevel)
+		return
+	}
+	if invite != nil {
+		// The invite is only used up, once the user was added to the members
+		if err := jamSession.CheckInvite(invite.ID); err != nil {
+			if err == jamsession.ErrInviteInvalid {
+				s.joinFailed(ip, jamLabel, joinInvalidInvite)
+				s.errUnauthorized(w, apierrors.ErrInviteInvalid, log.DebugLevel)
+				return
+			}
+			s.errInternalServerError(w, err, log.DebugLevel)
+			return
+		}
+	}
+
 	sessions.SetIdentifier(session, user.Identifier)
+	s.leaveLobby(r, user.Identifier)
+
+	// Invites were handed out by the members, so they don't need another approval
+	if invite == nil && settings.RequireApproval {
+		sessions.SetLobby(session, jamLabel)
+		if err := session.Save(r, w); err != nil {
+			s.errSessionSave(w, err)
+			return
+		}
+		settings, err := jamSession.RequestApproval(user.Identifier)
+		if err != nil {
+			s.errInternalServerError(w, err, log.DebugLevel)
+			return
+		}
+		jamSession.NotifyClients(&notifications.Message{
+			Event:   notifications.Lobby,
+			Message: s.getLobbyResponse(r.Context(), settings),
+		})
+		utils.EncodeJSONBody(w, types.PutJamJoinResponse{
+			Label:   jamLabel,
+			Pending: true,
+		})
+		return
+	}
 
 	if err := session.Save(r, w); err != nil {
 		s.errSessionSave(w, err)
 		return
 	}
 
-	members.Add(user.Identifier, permissions.Guest)
-
-	if err := jamSession.SetMembers(members); err != nil {
-		s.errInternalServerError(w, err, log.DebugLevel)
+	if invite != nil {
+		members, err = jamSession.JoinWithInvite(invite.ID, user.Identifier)
+	} else {
+		err = jamSession.UpdateMembers(func(current *jamsession.Members) error {
+			current.Add(user.Identifier, permissions.Guest)
+			members = current
+			return nil
+		})
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, jamsession.ErrInviteInvalid):
+			s.joinFailed(ip, jamLabel, joinInvalidInvite)
+			s.errUnauthorized(w, apierrors.ErrInviteInvalid, log.DebugLevel)
+		case errors.Is(err, jamsession.ErrUserBanned):
+			s.joinFailed(ip, jamLabel, joinBanned)
+			s.errForbidden(w, apierrors.ErrBanned, log.DebugLevel)
+		default:
+			s.errInternalServerError(w, err, log.DebugLevel)
+		}
 		return
 	}
 
@@ -420,9 +664,413 @@ func (s *Server) joinJamSession(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// joinFailure is the reason of a failed attempt to join a JamSession
+type joinFailure string
+
+const (
+	joinUnknownLabel  joinFailure = "unknown label"
+	joinWrongPassword joinFailure = "wrong password"
+	joinInvalidInvite joinFailure = "invalid invite"
+	joinBanned        joinFailure = "banned"
+)
+
+// joinLocked reports whether key is locked out because of too many failed attempts to join and writes the error to w
+func (s *Server) joinLocked(w http.ResponseWriter, key string) bool {
+	lockout, err := s.limiter.Locked(key)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return true
+	}
+	if lockout > 0 {
+		s.errTooManyRequests(w, apierrors.ErrTooManyAttempts, lockout, log.DebugLevel)
+		return true
+	}
+	return false
+}
+
+// joinFailed records a failed attempt to join the JamSession jamLabel from the client address ip.
+// Only wrong passwords count for the JamSession, all failures count for the client address.
+func (s *Server) joinFailed(ip string, jamLabel string, reason joinFailure) {
+	audit := log.WithFields(log.Fields{
+		"Audit":  "join",
+		"IP":     ip,
+		"Label":  jamLabel,
+		"Reason": reason,
+	})
+	audit.Warn("Failed attempt to join JamSession")
+
+	limits := map[string]ratelimit.Policy{joinIPKey(ip): joinIPPolicy}
+	switch reason {
+	case joinWrongPassword:
+		limits[joinLabelKey(jamLabel)] = joinLabelPolicy
+	case joinBanned:
+		// Banned users know the JamSession, so only the JamSession is locked for them
+		limits = map[string]ratelimit.Policy{joinBannedKey(jamLabel, ip): joinBannedPolicy}
+	}
+	for key, policy := range limits {
+		lockout, err := s.limiter.Fail(key, policy)
+		if err != nil {
+			log.Warn("Could not record failed attempt: ", err)
+			continue
+		}
+		if lockout > 0 {
+			audit.WithField("Lockout", lockout).Warn("Locked out ", key)
+		}
+	}
+}
+
+func joinIPKey(ip string) string {
+	return "join:ip:" + ip
+}
+
+func joinLabelKey(jamLabel string) string {
+	return "join:label:" + jamLabel
+}
+
+func joinBannedKey(jamLabel string, ip string) string {
+	return "join:banned:" + jamLabel + ":" + ip
+}
+
+func (s *Server) setHost(w http.ResponseWriter, r *http.Request) {
+	var body types.PutJamHostRequest
+	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+
+	jamSession := s.CurrentJamSession(r)
+	members, err := jamSession.TransferHost(r.Context(), body.Identifier, false)
+	switch {
+	case err == nil:
+	case errors.Is(err, jamsession.ErrNotMember), errors.Is(err, hub.ErrUserNotFound):
+		s.errBadRequest(w, apierrors.ErrMissingMember, log.DebugLevel)
+		return
+	case errors.Is(err, jamsession.ErrHostNotSpotify), errors.Is(err, jamsession.ErrAlreadyHost):
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	default:
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+
+	jamSession.NotifyClients(&notifications.Message{
+		Event:   notifications.Members,
+		Message: s.getMemberResponse(r.Context(), *members),
+	})
+	utils.EncodeJSONBody(w, types.PutJamHostResponse(s.getMemberResponse(r.Context(), *members)))
+}
+
+// inviteToken is handed out to join a JamSession with the invite ID
+type inviteToken struct {
+	Label string
+	ID    string
+}
+
+func (s *Server) inviteURL(token string) string {
+	inviteURL := *s.config.ClientAddresses[0]
+	inviteURL.Path = path.Join(inviteURL.Path, invitePath)
+	inviteURL.RawQuery = url.Values{"invite": {token}}.Encode()
+	return inviteURL.String()
+}
+
+func (s *Server) createInvite(w http.ResponseWriter, r *http.Request) {
+	var body types.PutJamInviteRequest
+	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+
+	ttl := inviteTTL
+	if body.ExpiresIn.Set && body.ExpiresIn.Valid {
+		ttl = time.Duration(body.ExpiresIn.Value) * time.Second
+	}
+	if ttl <= 0 || ttl > sessions.TokenMaxAge {
+		s.errBadRequest(w, apierrors.ErrExpiryInvalid, log.DebugLevel)
+		return
+	}
+
+	jamSession := s.CurrentJamSession(r)
+	invite, err := jamSession.CreateInvite(ttl, body.SingleUse)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+	token, err := s.store.EncodeToken(inviteTokenName, &inviteToken{
+		Label: jamSession.JamLabel,
+		ID:    invite.ID,
+	})
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+
+	utils.EncodeJSONBody(w, types.PutJamInviteResponse{
+		Token:     token,
+		URL:       s.inviteURL(token),
+		ExpiresAt: invite.ExpiresAt,
+		SingleUse: invite.SingleUse,
+	})
+}
+
+func (s *Server) getInviteQR(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	size, err := utils.QueryInt(r, "size", inviteQRSize)
+	if err != nil || size < inviteQRSizeMin || size > inviteQRSizeMax {
+		s.errBadRequest(w, apierrors.ErrQRSizeInvalid, log.DebugLevel)
+		return
+	}
+
+	// Only render invites of the JamSession, so the endpoint can't be used for arbitrary content
+	jamSession := s.CurrentJamSession(r)
+	var invite inviteToken
+	if err := s.store.DecodeToken(inviteTokenName, token, &invite); err != nil || invite.Label != jamSession.JamLabel {
+		s.errBadRequest(w, apierrors.ErrInviteInvalid, log.DebugLevel)
+		return
+	}
+
+	png, err := qrcode.Encode(s.inviteURL(token), qrcode.Medium, size)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	if _, err := w.Write(png); err != nil {
+		log.Debug("Could not write QR code: ", err)
+	}
+}
+
+func (s *Server) kickMember(w http.ResponseWriter, r *http.Request) {
+	var body types.PutJamKickRequest
+	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+
+	members, ok := s.kick(w, r, body.Identifier, false)
+	if !ok {
+		return
+	}
+	utils.EncodeJSONBody(w, types.PutJamKickResponse(s.getMemberResponse(r.Context(), *members)))
+}
+
+func (s *Server) banMember(w http.ResponseWriter, r *http.Request) {
+	var body types.PutJamBanRequest
+	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+
+	members, ok := s.kick(w, r, body.Identifier, true)
+	if !ok {
+		return
+	}
+	utils.EncodeJSONBody(w, types.PutJamBanResponse(s.getMemberResponse(r.Context(), *members)))
+}
+
+// kick removes the member identifier from the current JamSession and writes errors to w
+func (s *Server) kick(w http.ResponseWriter, r *http.Request, identifier string, ban bool) (*jamsession.Members, bool) {
+	user := s.CurrentUser(r)
+	jamSession := s.CurrentJamSession(r)
+	members, err := jamSession.Kick(user.Identifier, identifier, ban)
+	switch {
+	case err == nil:
+	case errors.Is(err, jamsession.ErrNotMember):
+		s.errBadRequest(w, apierrors.ErrMissingMember, log.DebugLevel)
+		return nil, false
+	case errors.Is(err, jamsession.ErrKickHost), errors.Is(err, jamsession.ErrKickSelf):
+		s.errBadRequest(w, err, log.DebugLevel)
+		return nil, false
+	case errors.Is(err, jamsession.ErrKickForbidden):
+		s.errForbidden(w, apierrors.ErrPermissionMissing, log.DebugLevel)
+		return nil, false
+	default:
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return nil, false
+	}
+
+	jamSession.NotifyClients(&notifications.Message{
+		Event:   notifications.Members,
+		Message: s.getMemberResponse(r.Context(), *members),
+	})
+	return members, true
+}
+
+// displayName returns the name of the user identifier, or an empty string, if the user was deleted in the meantime
+func (s *Server) displayName(ctx context.Context, identifier string) string {
+	user, err := s.users.GetUserByIdentifier(ctx, identifier)
+	if err != nil {
+		return ""
+	}
+	userInfo, err := user.GetInfo()
+	if err != nil {
+		return ""
+	}
+	return userInfo.UserName
+}
+
+func (s *Server) getBanResponse(ctx context.Context, settings *jamsession.Settings) types.JamBanResponse {
+	bans := make([]types.JamBan, 0, len(settings.Bans))
+	for _, identifier := range settings.Bans {
+		bans = append(bans, types.JamBan{
+			DisplayName: s.displayName(ctx, identifier),
+			Identifier:  identifier,
+		})
+	}
+	return types.JamBanResponse{Bans: bans}
+}
+
+func (s *Server) getBans(w http.ResponseWriter, r *http.Request) {
+	jamSession := s.CurrentJamSession(r)
+	settings, err := jamSession.GetSettings()
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+	utils.EncodeJSONBody(w, types.GetJamBansResponse(s.getBanResponse(r.Context(), settings)))
+}
+
+func (s *Server) unbanMember(w http.ResponseWriter, r *http.Request) {
+	var body types.DeleteJamBanRequest
+	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+
+	jamSession := s.CurrentJamSession(r)
+	settings, err := jamSession.Unban(body.Identifier)
+	switch err {
+	case nil:
+	case jamsession.ErrNotBanned:
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	default:
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+	utils.EncodeJSONBody(w, types.DeleteJamBanResponse(s.getBanResponse(r.Context(), settings)))
+}
+
+// leaveLobby stops waiting for the approval to join a JamSession, unless the user was approved in the meantime.
+// It reports whether the session of the user was changed.
+func (s *Server) leaveLobby(r *http.Request, identifier string) bool {
+	session := s.CurrentSession(r)
+	jamLabel, err := sessions.Lobby(session)
+	if err != nil {
+		return false
+	}
+	sessions.ClearLobby(session)
+	jamSession, err := s.jamFactory.GetJamSessionByLabel(jamLabel)
+	if err != nil {
+		return true
+	}
+	settings, err := jamSession.Withdraw(identifier)
+	if err != nil {
+		return true
+	}
+	jamSession.NotifyClients(&notifications.Message{
+		Event:   notifications.Lobby,
+		Message: s.getLobbyResponse(r.Context(), settings),
+	})
+	return true
+}
+
+func (s *Server) getLobbyResponse(ctx context.Context, settings *jamsession.Settings) types.JamLobbyResponse {
+	pending := make([]types.JamPending, 0, len(settings.Pending))
+	for _, identifier := range settings.Pending {
+		pending = append(pending, types.JamPending{
+			DisplayName: s.displayName(ctx, identifier),
+			Identifier:  identifier,
+		})
+	}
+	return types.JamLobbyResponse{Pending: pending}
+}
+
+func (s *Server) getLobby(w http.ResponseWriter, r *http.Request) {
+	jamSession := s.CurrentJamSession(r)
+	settings, err := jamSession.GetSettings()
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+	utils.EncodeJSONBody(w, types.GetJamLobbyResponse(s.getLobbyResponse(r.Context(), settings)))
+}
+
+func (s *Server) approveJoin(w http.ResponseWriter, r *http.Request) {
+	var body types.PutJamApproveRequest
+	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+
+	jamSession := s.CurrentJamSession(r)
+	settings, err := jamSession.Approve(body.Identifier)
+	switch err {
+	case nil:
+	case jamsession.ErrNotPending:
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	case jamsession.ErrUserBanned:
+		s.errForbidden(w, apierrors.ErrBanned, log.DebugLevel)
+		return
+	default:
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+	members, err := jamSession.GetMembers()
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+
+	lobby := s.getLobbyResponse(r.Context(), settings)
+	jamSession.NotifyClients(&notifications.Message{
+		Event:   notifications.Members,
+		Message: s.getMemberResponse(r.Context(), *members),
+	})
+	jamSession.NotifyClients(&notifications.Message{
+		Event:   notifications.Lobby,
+		Message: lobby,
+	})
+	utils.EncodeJSONBody(w, types.PutJamApproveResponse(lobby))
+}
+
+func (s *Server) rejectJoin(w http.ResponseWriter, r *http.Request) {
+	var body types.PutJamRejectRequest
+	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+
+	jamSession := s.CurrentJamSession(r)
+	settings, err := jamSession.Reject(body.Identifier)
+	switch err {
+	case nil:
+	case jamsession.ErrNotPending:
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	default:
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+
+	lobby := s.getLobbyResponse(r.Context(), settings)
+	jamSession.NotifyClients(&notifications.Message{
+		Event:   notifications.Lobby,
+		Message: lobby,
+	})
+	utils.EncodeJSONBody(w, types.PutJamRejectResponse(lobby))
+}
+
 func (s *Server) leaveJamSession(w http.ResponseWriter, r *http.Request) {
 
 	user := s.CurrentUser(r)
+	if s.leaveLobby(r, user.Identifier) {
+		if err := s.CurrentSession(r).Save(r, w); err != nil {
+			s.errSessionSave(w, err)
+			return
+		}
+	}
+
 	if jamSession, err := s.jamFactory.GetJamSessionByUser(user); err == nil {
 		members, err := jamSession.GetMembers()
 		if err != nil {
@@ -437,7 +1085,29 @@ func (s *Server) leaveJamSession(w http.ResponseWriter, r *http.Request) {
 		isHost := member.HasPermissions(permissions.Host)
 
 		if isHost {
-			members.Remove(user.Identifier)
+			settings, err := jamSession.GetSettings()
+			if err != nil {
+				s.errInternalServerError(w, err, log.DebugLevel)
+				return
+			}
+			// Keep the party going with another host, if possible
+			if settings.AutoHandover {
+				members, err := jamSession.Handover(r.Context())
+				if err == nil {
+					jamSession.NotifyClients(&notifications.Message{
+						Event:   notifications.Members,
+						Message: s.getMemberResponse(r.Context(), *members),
+					})
+					utils.EncodeJSONBody(w, types.GetJamLeaveResponse{
+						Success: true,
+					})
+					return
+				}
+				if err != jamsession.ErrNoHandoverCandidate {
+					s.errInternalServerError(w, err, log.DebugLevel)
+					return
+				}
+			}
 			jamSession.NotifyClients(&notifications.Message{
 				Event:   notifications.Close,
 				Message: notifications.HostLeft,
@@ -447,16 +1117,20 @@ func (s *Server) leaveJamSession(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		} else {
-			members.Remove(user.Identifier)
+			err := jamSession.UpdateMembers(func(current *jamsession.Members) error {
+				current.Remove(user.Identifier)
+				members = current
+				return nil
+			})
+			if err != nil {
+				s.errInternalServerError(w, err, log.DebugLevel)
+				return
+			}
 			jamSession.NotifyClients(&notifications.Message{
 				Event:   notifications.Members,
 				Message: s.getMemberResponse(r.Context(), *members),
 			})
 		}
-		if err := jamSession.SetMembers(members); err != nil {
-			s.errInternalServerError(w, err, log.DebugLevel)
-			return
-		}
 	}
 
 	utils.EncodeJSONBody(w, types.GetJamLeaveResponse{
@@ -472,16 +1146,15 @@ func (s *Server) search(w http.ResponseWriter, r *http.Request) {
 
 	jamSession := s.CurrentJamSession(r)
 
-	entry, err := s.jamFactory.Search(r.Context(), jamSession, body.SearchType, body.SearchText)
+	searchResult, err := s.jamFactory.Search(r.Context(), jamSession, body.SearchType, body.SearchText)
 	if err != nil {
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
 	}
 
-	searchResult, ok := entry.(*spotify.SearchResult)
-	if !ok {
-		s.errInternalServerError(w, err, log.ErrorLevel)
-		return
+	blocked := make(map[string]string, len(searchResult.Blocked))
+	for trackID, reason := range searchResult.Blocked {
+		blocked[trackID] = string(reason)
 	}
 
 	utils.EncodeJSONBody(w, types.PutSpotifySearchResponse{
@@ -489,5 +1162,6 @@ func (s *Server) search(w http.ResponseWriter, r *http.Request) {
 		Albums:    searchResult.Albums,
 		Playlists: searchResult.Playlists,
 		Tracks:    searchResult.Tracks,
+		Blocked:   blocked,
 	})
 }
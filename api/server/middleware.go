@@ -1,6 +1,10 @@
 package server
 
 import (
+	"errors"
+
+	"github.com/justinas/alice"
+
 	apierrors "github.com/jamfactoryapp/jamfactory-backend/api/errors"
 	"github.com/jamfactoryapp/jamfactory-backend/api/sessions"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
@@ -87,24 +91,59 @@ func (s *Server) jamSessionRequired(next http.Handler) http.Handler {
 	})
 }
 
-func (s *Server) hostRequired(next http.Handler) http.Handler {
+// lobbyRequired only lets users pass, who wait for the approval to join a JamSession
+func (s *Server) lobbyRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := s.CurrentUser(r)
-		jamSession := s.CurrentJamSession(r)
-		members, err := jamSession.GetMembers()
+		jamLabel, err := sessions.Lobby(s.CurrentSession(r))
+		if err != nil {
+			s.errUnauthorized(w, apierrors.ErrNotWaiting, log.TraceLevel)
+			return
+		}
+		jamSession, err := s.jamFactory.GetJamSessionByLabel(jamLabel)
+		if err != nil {
+			s.errUnauthorized(w, err, log.TraceLevel)
+			return
+		}
+		settings, err := jamSession.GetSettings()
 		if err != nil {
 			s.errInternalServerError(w, err, log.DebugLevel)
 			return
 		}
-		member, err := members.Get(user.Identifier)
-		if err != nil || !member.HasPermissions(permissions.Host) {
-			s.errUnauthorized(w, apierrors.ErrUserTypeInvalid, log.DebugLevel)
+		if !settings.Waiting(user.Identifier) {
+			s.errUnauthorized(w, apierrors.ErrNotWaiting, log.TraceLevel)
 			return
 		}
+
+		ctx := jamsession.NewContext(r.Context(), jamSession)
+		r = r.WithContext(ctx)
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// permissionRequired only lets members of the current JamSession pass, who were granted all permissions p
+func (s *Server) permissionRequired(p ...permissions.Permission) alice.Constructor {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			member, err := s.CurrentMember(r)
+			if errors.Is(err, jamsession.ErrNotMember) {
+				s.errForbidden(w, apierrors.ErrMissingMember, log.DebugLevel)
+				return
+			}
+			if err != nil {
+				s.errInternalServerError(w, err, log.WarnLevel)
+				return
+			}
+			if !member.Can(p...) {
+				s.errForbidden(w, apierrors.ErrPermissionMissing, log.DebugLevel)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (s *Server) nonMemberRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := s.CurrentUser(r)
@@ -9,8 +9,15 @@ import (
 
 	"github.com/jamfactoryapp/jamfactory-backend/api/types"
 	"github.com/jamfactoryapp/jamfactory-backend/api/utils"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	pkgqueue "github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
 	log "github.com/sirupsen/logrus"
-	"github.com/zmb3/spotify/v2"
+)
+
+const (
+	historyPageLimit    = 50
+	historyPageLimitMax = 100
 )
 
 func (s *Server) getQueue(w http.ResponseWriter, r *http.Request) {
@@ -21,25 +28,42 @@ func (s *Server) getQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	voteID := s.CurrentVoteID(r)
+	quota, err := jamSession.RemainingQuota(queue, voteID)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
 	tracks := queue.For(voteID)
 
 	utils.EncodeJSONBody(w, types.GetQueueResponse{
-		Tracks: tracks,
+		Tracks:   tracks,
+		Quota:    quota,
+		Fallback: queue.UpcomingFallback(),
 	})
 }
 
 func (s *Server) getQueueHistory(w http.ResponseWriter, r *http.Request) {
+	offset, err := utils.QueryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		s.errBadRequest(w, apierrors.ErrPaginationInvalid, log.DebugLevel)
+		return
+	}
+	limit, err := utils.QueryInt(r, "limit", historyPageLimit)
+	if err != nil || limit < 1 || limit > historyPageLimitMax {
+		s.errBadRequest(w, apierrors.ErrPaginationInvalid, log.DebugLevel)
+		return
+	}
+
 	jamSession := s.CurrentJamSession(r)
 	queue, err := jamSession.GetQueue()
 	if err != nil {
 		s.errInternalServerError(w, err, log.WarnLevel)
 		return
 	}
-	voteID := s.CurrentVoteID(r)
-	history := queue.GetHistory(voteID)
 
 	utils.EncodeJSONBody(w, types.GetQueueHistoryResponse{
-		History: history,
+		History: queue.GetHistory(offset, limit),
+		Total:   len(queue.History),
 	})
 }
 
@@ -76,24 +100,22 @@ func (s *Server) exportQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	voteID := s.CurrentVoteID(r)
-	tracks := make([]types.Song, 0)
+	ids := make([]string, 0)
 	if body.IncludeHistory {
-		tracks = append(tracks, queue.GetHistory(voteID)...)
+		for _, played := range queue.History {
+			ids = append(ids, played.ID)
+		}
 	}
 
 	if body.IncludeQueue {
-		tracks = append(tracks, queue.For(voteID)...)
+		for _, so := range queue.Songs {
+			ids = append(ids, so.ID)
+		}
 	}
-	if len(tracks) == 0 {
+	if len(ids) == 0 {
 		s.errBadRequest(w, errors.New("No songs to export"), log.DebugLevel)
 		return
 	}
-
-	ids := make([]spotify.ID, len(tracks))
-	for i := range tracks {
-		ids[i] = tracks[i].Song.ID
-	}
 	desc := settings.Name + "  exported queue at " + time.Now().Format("02.01.2006, 15:01") + ". https://jamfactory.app"
 	err = host.CreatePlaylist(r.Context(), body.PlaylistName, desc, ids)
 	if err != nil {
@@ -127,7 +149,8 @@ func (s *Server) addCollection(w http.ResponseWriter, r *http.Request) {
 	tracks := queue.For(voteID)
 
 	utils.EncodeJSONBody(w, types.PutQueuePlaylistsResponse{
-		Tracks: tracks,
+		Tracks:   tracks,
+		Fallback: queue.UpcomingFallback(),
 	})
 }
 
@@ -140,7 +163,42 @@ func (s *Server) vote(w http.ResponseWriter, r *http.Request) {
 	jamSession := s.CurrentJamSession(r)
 	voteID := s.CurrentVoteID(r)
 
-	if err := jamSession.Vote(r.Context(), body.TrackID, voteID); err != nil {
+	currentQueue, err := jamSession.GetQueue()
+	if err != nil {
+		s.errInternalServerError(w, err, log.WarnLevel)
+		return
+	}
+	member, err := s.CurrentMember(r)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+	// Voting for a song, which isn't queued yet, adds it to the queue
+	if !body.Downvote && !currentQueue.Contains(body.TrackID) && !member.Can(permissions.AddSong) {
+		s.errForbidden(w, apierrors.ErrPermissionMissing, log.DebugLevel)
+		return
+	}
+
+	if body.Downvote {
+		err = jamSession.Downvote(body.TrackID, voteID)
+		if err == pkgqueue.ErrSongNotFound {
+			s.errNotFound(w, err, log.DebugLevel)
+			return
+		}
+		if err != nil {
+			s.errInternalServerError(w, err, log.DebugLevel)
+			return
+		}
+	} else if err := jamSession.Vote(r.Context(), body.TrackID, voteID); err != nil {
+		var quotaErr *pkgqueue.QuotaError
+		if errors.As(err, &quotaErr) {
+			s.errTooManyRequests(w, quotaErr, quotaErr.RetryAfter, log.DebugLevel)
+			return
+		}
+		if errors.Is(err, jamsession.ErrTrackBlocked) || errors.Is(err, pkgqueue.ErrRecentlyPlayed) {
+			s.errForbidden(w, err, log.DebugLevel)
+			return
+		}
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
 	}
@@ -149,14 +207,38 @@ func (s *Server) vote(w http.ResponseWriter, r *http.Request) {
 		s.errInternalServerError(w, err, log.WarnLevel)
 		return
 	}
+	quota, err := jamSession.RemainingQuota(queue, voteID)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
 
 	tracks := queue.For(voteID)
 
 	utils.EncodeJSONBody(w, types.PutQueueVoteResponse{
-		Tracks: tracks,
+		Tracks:   tracks,
+		Quota:    quota,
+		Fallback: queue.UpcomingFallback(),
 	})
 }
 
+func (s *Server) voteSkip(w http.ResponseWriter, r *http.Request) {
+	jamSession := s.CurrentJamSession(r)
+	voteID := s.CurrentVoteID(r)
+
+	progress, err := jamSession.VoteSkip(r.Context(), voteID)
+	if err == jamsession.ErrNothingPlaying {
+		s.errBadRequest(w, err, log.DebugLevel)
+		return
+	}
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
+
+	utils.EncodeJSONBody(w, types.PutQueueSkipResponse(*progress))
+}
+
 func (s *Server) deleteSong(w http.ResponseWriter, r *http.Request) {
 	var body types.DeleteQueueSongRequest
 	if err := utils.DecodeJSONBody(w, r, &body); err != nil {
@@ -164,21 +246,22 @@ func (s *Server) deleteSong(w http.ResponseWriter, r *http.Request) {
 	}
 
 	jamSession := s.CurrentJamSession(r)
-	queue, err := jamSession.GetQueue()
-	if err != nil {
-		s.errInternalServerError(w, err, log.WarnLevel)
-		return
-	}
 	voteID := s.CurrentVoteID(r)
 
 	if err := jamSession.DeleteSong(body.TrackID); err != nil {
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
 	}
+	queue, err := jamSession.GetQueue()
+	if err != nil {
+		s.errInternalServerError(w, err, log.WarnLevel)
+		return
+	}
 
 	tracks := queue.For(voteID)
 
 	utils.EncodeJSONBody(w, types.DeleteQueueSongResponse{
-		Tracks: tracks,
+		Tracks:   tracks,
+		Fallback: queue.UpcomingFallback(),
 	})
 }
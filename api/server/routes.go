@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/gorilla/mux"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
 	"github.com/justinas/alice"
 )
 
@@ -27,12 +28,22 @@ const (
 	jamSessionPlay     = "/play"
 	jamSessionPlayback = "/playback"
 	jamSessionMembers  = "/members"
+	jamSessionHost     = "/host"
+	jamSessionKick     = "/members/kick"
+	jamSessionBan      = "/members/ban"
+	jamSessionInvite   = "/invite"
+	jamSessionInviteQR = "/invite/qr"
+	jamSessionLobby    = "/lobby"
+	jamSessionApprove  = "/lobby/approve"
+	jamSessionReject   = "/lobby/reject"
 	jamSessionSearch   = "/search"
+	jamSessionEvents   = "/events"
 
 	queuePath       = "/queue"
 	queueIndex      = ""
 	queueCollection = "/collection"
 	queueVote       = "/vote"
+	queueSkip       = "/skip"
 	queueDelete     = "/delete"
 	queueHistory    = "/history"
 	queueExport     = "/export"
@@ -44,6 +55,7 @@ const (
 
 	websocketPath  = "/ws"
 	websocketIndex = ""
+	websocketLobby = "/lobby"
 )
 
 func (s *Server) initRoutes() {
@@ -101,11 +113,11 @@ func (s *Server) registerUserRoutes(r *mux.Router, chain alice.Chain) {
 
 	// GET: /api/v1/me/devices
 	r.Methods("GET").Path(userDevices).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.getUserDevices))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ControlPlayback)).ThenFunc(s.getUserDevices))
 
 	// GET: /api/v1/me/playlists
 	r.Methods("GET").Path(userPlaylists).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.getUserPlaylists))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.AddCollection)).ThenFunc(s.getUserPlaylists))
 }
 
 func (s *Server) registerJamSessionRoutes(r *mux.Router, chain alice.Chain) {
@@ -123,7 +135,7 @@ func (s *Server) registerJamSessionRoutes(r *mux.Router, chain alice.Chain) {
 
 	// PUT: /api/v1/jam/play
 	r.Methods("PUT").Path(jamSessionPlay).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.playSong))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ControlPlayback)).ThenFunc(s.playSong))
 
 	// GET: /api/v1/jam/
 	r.Methods("GET").Path(jamSessionIndex).Handler(
@@ -131,7 +143,7 @@ func (s *Server) registerJamSessionRoutes(r *mux.Router, chain alice.Chain) {
 
 	// PUT: /api/v1/jam/
 	r.Methods("PUT").Path(jamSessionIndex).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.setJamSession))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ChangeSettings)).ThenFunc(s.setJamSession))
 
 	// PUT: /api/v1/jam/search
 	r.Methods("PUT").Path(jamSessionSearch).Handler(
@@ -143,7 +155,7 @@ func (s *Server) registerJamSessionRoutes(r *mux.Router, chain alice.Chain) {
 
 	// PUT: /api/v1/jam/playback
 	r.Methods("PUT").Path(jamSessionPlayback).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.setPlayback))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ControlPlayback)).ThenFunc(s.setPlayback))
 
 	// GET: /api/v1/jam/members
 	r.Methods("GET").Path(jamSessionMembers).Handler(
@@ -151,7 +163,51 @@ func (s *Server) registerJamSessionRoutes(r *mux.Router, chain alice.Chain) {
 
 	// PUT: /api/v1/jam/members
 	r.Methods("PUT").Path(jamSessionMembers).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.setMembers))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.setMembers))
+
+	// PUT: /api/v1/jam/members/kick
+	r.Methods("PUT").Path(jamSessionKick).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.kickMember))
+
+	// GET: /api/v1/jam/members/ban
+	r.Methods("GET").Path(jamSessionBan).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.getBans))
+
+	// PUT: /api/v1/jam/members/ban
+	r.Methods("PUT").Path(jamSessionBan).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.banMember))
+
+	// DELETE: /api/v1/jam/members/ban
+	r.Methods("DELETE").Path(jamSessionBan).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.unbanMember))
+
+	// PUT: /api/v1/jam/invite
+	r.Methods("PUT").Path(jamSessionInvite).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.createInvite))
+
+	// GET: /api/v1/jam/invite/qr
+	r.Methods("GET").Path(jamSessionInviteQR).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.getInviteQR))
+
+	// GET: /api/v1/jam/lobby
+	r.Methods("GET").Path(jamSessionLobby).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.getLobby))
+
+	// PUT: /api/v1/jam/lobby/approve
+	r.Methods("PUT").Path(jamSessionApprove).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.approveJoin))
+
+	// PUT: /api/v1/jam/lobby/reject
+	r.Methods("PUT").Path(jamSessionReject).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ManageMembers)).ThenFunc(s.rejectJoin))
+
+	// PUT: /api/v1/jam/host
+	r.Methods("PUT").Path(jamSessionHost).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.Host)).ThenFunc(s.setHost))
+
+	// GET: /api/v1/jam/events
+	r.Methods("GET").Path(jamSessionEvents).Handler(
+		chain.Append(s.jamSessionRequired).ThenFunc(s.jamEvents))
 }
 
 func (s *Server) registerQueueRoutes(r *mux.Router, chain alice.Chain) {
@@ -161,15 +217,19 @@ func (s *Server) registerQueueRoutes(r *mux.Router, chain alice.Chain) {
 
 	// PUT: /api/v1/queue/collection
 	r.Methods("PUT").Path(queueCollection).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.addCollection))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.AddCollection)).ThenFunc(s.addCollection))
 
 	// PUT: /api/v1/queue/vote
 	r.Methods("PUT").Path(queueVote).Handler(
-		chain.Append(s.jamSessionRequired).ThenFunc(s.vote))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.Vote)).ThenFunc(s.vote))
+
+	// PUT: /api/v1/queue/skip
+	r.Methods("PUT").Path(queueSkip).Handler(
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.Vote)).ThenFunc(s.voteSkip))
 
 	// DELETE: /api/v1/queue/delete
 	r.Methods("DELETE").Path(queueDelete).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.deleteSong))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.DeleteSong)).ThenFunc(s.deleteSong))
 
 	// GET: /api/v1/queue/history
 	r.Methods("GET").Path(queueHistory).Handler(
@@ -177,19 +237,19 @@ func (s *Server) registerQueueRoutes(r *mux.Router, chain alice.Chain) {
 
 	// PUT: /api/v1/queue/export
 	r.Methods("PUT").Path(queueExport).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.exportQueue))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.Host)).ThenFunc(s.exportQueue))
 }
 
 func (s *Server) registerSpotifyRoutes(r *mux.Router, chain alice.Chain) {
 	// TODO: Deprecate endpoint in favour of /api/v1/user/devices
 	// GET: /api/v1/spotify/devices
 	r.Methods("GET").Path(spotifyDevices).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.getUserDevices))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.ControlPlayback)).ThenFunc(s.getUserDevices))
 
 	// TODO: Deprecate endpoint in favour of /api/v1/user/playlists
 	// GET: /api/v1/spotify/playlists
 	r.Methods("GET").Path(spotifyPlaylist).Handler(
-		chain.Append(s.jamSessionRequired, s.hostRequired).ThenFunc(s.getUserPlaylists))
+		chain.Append(s.jamSessionRequired, s.permissionRequired(permissions.AddCollection)).ThenFunc(s.getUserPlaylists))
 
 	// TODO: Deprecate endpoint in favour of /api/v1/jam/search
 	// PUT: /api/v1/spotify/search
@@ -201,4 +261,8 @@ func (s *Server) registerWebsocketRoutes(r *mux.Router, chain alice.Chain) {
 	// GET /ws
 	r.Methods("GET").Path(websocketIndex).Handler(
 		chain.Append(s.jamSessionRequired).ThenFunc(s.websocketHandler))
+
+	// GET /ws/lobby
+	r.Methods("GET").Path(websocketLobby).Handler(
+		chain.Append(s.lobbyRequired).ThenFunc(s.websocketLobbyHandler))
 }
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/gob"
 	"fmt"
@@ -10,7 +11,9 @@ import (
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamfactory"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/ratelimit"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
 	"net/http"
 	"time"
@@ -19,13 +22,12 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/cache"
 	log "github.com/sirupsen/logrus"
-	"github.com/zmb3/spotify/v2"
 	"golang.org/x/oauth2"
 )
 
 func init() {
 	gob.Register(&oauth2.Token{})
-	gob.Register(&spotify.SearchResult{})
+	gob.Register(&provider.SearchResult{})
 	gob.Register(users.UserType(""))
 	gob.Register(users.User{})
 	gob.Register(jamsession.Settings{})
@@ -48,6 +50,7 @@ type Server struct {
 	cache         *cache.Cache
 	authenticator *authenticator.Authenticator
 	jamFactory    *jamfactory.JamFactory
+	limiter       ratelimit.Limiter
 	upgrader      websocket.Upgrader
 }
 
@@ -66,6 +69,7 @@ func NewServer(pattern string, config *config.Config, sessionStore *sessions.Sto
 		store:         sessionStore,
 		users:         users,
 		jamFactory:    jamFactory,
+		limiter:       ratelimit.NewMemory(users.Clock),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
@@ -91,6 +95,11 @@ func (s *Server) RunTLS(certFile string, keyFile string) error {
 	return s.server.ListenAndServeTLS(certFile, keyFile)
 }
 
+// Shutdown stops accepting requests and waits for in-flight requests until ctx is done
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
 func (s *Server) WithPort(port int) *Server {
 	s.server.Addr = fmt.Sprintf(":%d", port)
 	return s
@@ -101,6 +110,12 @@ func (s *Server) WithCache(cache *cache.Cache) *Server {
 	return s
 }
 
+// WithLimiter sets the Limiter for failed attempts. Backend instances sharing a store should share the Limiter
+func (s *Server) WithLimiter(limiter ratelimit.Limiter) *Server {
+	s.limiter = limiter
+	return s
+}
+
 func (s *Server) WithTLS(config *tls.Config) *Server {
 	s.server.TLSConfig = config
 	return s
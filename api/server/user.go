@@ -163,10 +163,15 @@ func (s *Server) setUserPlayback(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// getUserPlaylists returns the playlists of the host, as collections are added with the Spotify account of the host
 func (s *Server) getUserPlaylists(w http.ResponseWriter, r *http.Request) {
-	user := s.CurrentUser(r)
+	host, err := s.CurrentHost(r)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
 
-	playlists, err := user.Playlists(r.Context())
+	playlists, err := host.Playlists(r.Context())
 	if err != nil {
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
@@ -177,10 +182,15 @@ func (s *Server) getUserPlaylists(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// getUserDevices returns the devices of the host, as the playback is controlled on the Spotify account of the host
 func (s *Server) getUserDevices(w http.ResponseWriter, r *http.Request) {
-	user := s.CurrentUser(r)
+	host, err := s.CurrentHost(r)
+	if err != nil {
+		s.errInternalServerError(w, err, log.DebugLevel)
+		return
+	}
 
-	devices, err := user.Devices(r.Context())
+	devices, err := host.Devices(r.Context())
 	if err != nil {
 		s.errInternalServerError(w, err, log.DebugLevel)
 		return
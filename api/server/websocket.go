@@ -1,18 +1,46 @@
 package server
 
 import (
-	log "github.com/sirupsen/logrus"
 	"net/http"
+	"strconv"
+
+	apierrors "github.com/jamfactoryapp/jamfactory-backend/api/errors"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	log "github.com/sirupsen/logrus"
 )
 
 func (s *Server) websocketHandler(w http.ResponseWriter, r *http.Request) {
 	jamSession := s.CurrentJamSession(r)
 
+	// Reconnecting clients pass the stream and the sequence number of the last message they received
+	var resume *notifications.Resume
+	if stream := r.URL.Query().Get("stream"); stream != "" {
+		seq, err := strconv.ParseUint(r.URL.Query().Get("seq"), 10, 64)
+		if err != nil {
+			s.errBadRequest(w, apierrors.ErrResumeInvalid, log.DebugLevel)
+			return
+		}
+		resume = &notifications.Resume{Stream: stream, Seq: seq}
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.errInternalServerError(w, err, log.ErrorLevel)
+		return
+	}
+
+	jamSession.IntroduceClient(conn, s.CurrentUser(r).Identifier, resume)
+}
+
+// websocketLobbyHandler connects users, who wait for approval, to the JamSession they want to join
+func (s *Server) websocketLobbyHandler(w http.ResponseWriter, r *http.Request) {
+	jamSession := s.CurrentJamSession(r)
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.errInternalServerError(w, err, log.ErrorLevel)
 		return
 	}
 
-	jamSession.IntroduceClient(conn)
+	jamSession.IntroduceLobbyClient(conn, s.CurrentUser(r).Identifier)
 }
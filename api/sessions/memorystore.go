@@ -0,0 +1,64 @@
+package sessions
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+type memoryBackend struct {
+	sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemorySessionStore(keyPairsFile string, sameSite http.SameSite, secure bool) *Store {
+	backend := &memoryBackend{
+		entries: make(map[string]memoryEntry),
+	}
+	return NewStore(backend, keyPairsFile, sameSite, secure)
+}
+
+func (b *memoryBackend) Load(id string) ([]byte, error) {
+	b.Lock()
+	defer b.Unlock()
+	entry, ok := b.entries[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(b.entries, id)
+		return nil, nil
+	}
+	// Loading a session refreshes its expiry like the redis EXPIRE call
+	entry.expires = time.Now().Add(sessionMaxAge * time.Second)
+	b.entries[id] = entry
+	return entry.data, nil
+}
+
+func (b *memoryBackend) Save(id string, data []byte, maxAge int) error {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	for k, entry := range b.entries {
+		if now.After(entry.expires) {
+			delete(b.entries, k)
+		}
+	}
+	b.entries[id] = memoryEntry{
+		data:    data,
+		expires: now.Add(time.Duration(maxAge) * time.Second),
+	}
+	return nil
+}
+
+func (b *memoryBackend) Delete(id string) error {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.entries, id)
+	return nil
+}
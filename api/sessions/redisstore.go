@@ -10,6 +10,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/gorilla/securecookie"
@@ -29,20 +30,43 @@ const (
 	minCookieKeyPairsCount     = 4
 )
 
+// TokenMaxAge is the time after which tokens created by Store.EncodeToken expire
+const TokenMaxAge = cookieMaxAge * time.Second
+
+// Backend persists the serialized values of sessions
+type Backend interface {
+	Load(id string) ([]byte, error)
+	Save(id string, data []byte, maxAge int) error
+	Delete(id string) error
+}
+
 type Store struct {
 	sync.Mutex
-	pool          *redis.Pool
-	redisKey      pkgredis.Key
+	backend       Backend
 	options       *sessions.Options
 	codecs        []securecookie.Codec
+	tokenCodecs   []securecookie.Codec
 	keyPairsCount int
 	keyPairsFile  string
 }
 
+type redisBackend struct {
+	pool     *redis.Pool
+	redisKey pkgredis.Key
+}
+
 func NewRedisSessionStore(pool *redis.Pool, keyPairsFile string, sameSite http.SameSite, secure bool) *Store {
-	redisStore := &Store{
+	backend := &redisBackend{
 		pool:     pool,
 		redisKey: pkgredis.Key{}.Append(defaultRedisSessionKey),
+	}
+	return NewStore(backend, keyPairsFile, sameSite, secure)
+}
+
+// NewStore creates a session store persisting sessions in backend
+func NewStore(backend Backend, keyPairsFile string, sameSite http.SameSite, secure bool) *Store {
+	store := &Store{
+		backend: backend,
 		options: &sessions.Options{
 			Path:     "/",
 			MaxAge:   cookieMaxAge,
@@ -53,9 +77,9 @@ func NewRedisSessionStore(pool *redis.Pool, keyPairsFile string, sameSite http.S
 		keyPairsFile:  keyPairsFile,
 	}
 
-	redisStore.MaxAge(redisStore.options.MaxAge)
-	redisStore.LoadCookieKeyPairs()
-	return redisStore
+	store.LoadCookieKeyPairs()
+	store.MaxAge(store.options.MaxAge)
+	return store
 }
 
 func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
@@ -117,6 +141,17 @@ func (s *Store) MaxAge(age int) {
 	}
 }
 
+// EncodeToken signs and encrypts value with the cookie key pairs. name separates tokens of different purposes
+// and has to be passed to DecodeToken. Tokens expire after TokenMaxAge.
+func (s *Store) EncodeToken(name string, value interface{}) (string, error) {
+	return securecookie.EncodeMulti(name, value, s.tokenCodecs...)
+}
+
+// DecodeToken verifies and decrypts a token created by EncodeToken into dst
+func (s *Store) DecodeToken(name string, token string, dst interface{}) error {
+	return securecookie.DecodeMulti(name, token, dst, s.tokenCodecs...)
+}
+
 func (s *Store) LoadCookieKeyPairs() {
 	var keyPairs [][]byte
 	if utils.FileExists(s.keyPairsFile) {
@@ -126,47 +161,75 @@ func (s *Store) LoadCookieKeyPairs() {
 		s.writeCookieKeyPairs(keyPairs)
 	}
 	s.codecs = securecookie.CodecsFromPairs(keyPairs...)
+	// Tokens expire after TokenMaxAge, independent of the max age of the cookies
+	s.tokenCodecs = securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range s.tokenCodecs {
+		if secureCookie, ok := codec.(*securecookie.SecureCookie); ok {
+			secureCookie.MaxAge(int(TokenMaxAge / time.Second))
+		}
+	}
 }
 
 func (s *Store) load(session *sessions.Session) (bool, error) {
 	s.Lock()
 	defer s.Unlock()
 
-	conn := s.pool.Get()
-	reply, err := conn.Do("GET", s.redisKey.Append(session.ID))
+	data, err := s.backend.Load(session.ID)
 	if err != nil {
 		return false, err
 	}
-	if reply == nil {
+	if data == nil {
 		return false, errors.New("JamSessions: session not found")
 	}
-	if data, ok := reply.([]byte); ok {
-		err = s.deserialize(data, session)
-	} else {
-		err = errors.New("JamSessions: Failed to convert session data from interface{} to []bytes")
-	}
-
-	if reply, err = conn.Do("EXPIRE", s.redisKey.Append(session.ID), sessionMaxAge); err != nil {
-		log.Error("JamSessions: Failed to update expiry")
-	}
-
-	return true, err
+	return true, s.deserialize(data, session)
 }
 
 func (s *Store) save(session *sessions.Session) error {
-	conn := s.pool.Get()
 	serialized, err := s.serialize(session)
 	if err != nil {
 		return err
 	}
-	reply, err := conn.Do("SET", s.redisKey.Append(session.ID), serialized, "EX", sessionMaxAge)
+	return s.backend.Save(session.ID, serialized, sessionMaxAge)
+}
+
+func (s *Store) delete(session *sessions.Session) error {
+	return s.backend.Delete(session.ID)
+}
+
+func (b *redisBackend) Load(id string) ([]byte, error) {
+	conn := b.pool.Get()
+	defer conn.Close()
+	reply, err := conn.Do("GET", b.redisKey.Append(id))
+	if err != nil {
+		return nil, err
+	}
+	if reply == nil {
+		return nil, nil
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, errors.New("JamSessions: Failed to convert session data from interface{} to []bytes")
+	}
+
+	if _, err = conn.Do("EXPIRE", b.redisKey.Append(id), sessionMaxAge); err != nil {
+		log.Error("JamSessions: Failed to update expiry")
+	}
+
+	return data, nil
+}
+
+func (b *redisBackend) Save(id string, data []byte, maxAge int) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+	reply, err := conn.Do("SET", b.redisKey.Append(id), data, "EX", maxAge)
 	log.Trace("redis reply (DO SET): ", reply, " with err: ", err)
 	return err
 }
 
-func (s *Store) delete(session *sessions.Session) error {
-	conn := s.pool.Get()
-	_, err := conn.Do("DEL", s.redisKey.Append(session.ID))
+func (b *redisBackend) Delete(id string) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("DEL", b.redisKey.Append(id))
 	return err
 }
 
@@ -13,6 +13,7 @@ const key contextKey = "Session"
 const (
 	identifierKey = "GetIdentifier"
 	originKey     = "Origin"
+	lobbyKey      = "Lobby"
 )
 
 func NewContext(ctx context.Context, session *sessions.Session) context.Context {
@@ -55,6 +56,19 @@ func Identifier(session *sessions.Session) (string, error) {
 	return identifier, nil
 }
 
+// Lobby returns the label of the JamSession, in whose lobby the user waits for approval
+func Lobby(session *sessions.Session) (string, error) {
+	lobbyVal := session.Values[lobbyKey]
+	if lobbyVal == nil {
+		return "", errors.ErrLobbyMissing
+	}
+	lobby, ok := lobbyVal.(string)
+	if !ok {
+		return "", errors.ErrLobbyMalformed
+	}
+	return lobby, nil
+}
+
 func SetOrigin(session *sessions.Session, origin string) {
 	session.Values[originKey] = origin
 }
@@ -62,3 +76,11 @@ func SetOrigin(session *sessions.Session, origin string) {
 func SetIdentifier(session *sessions.Session, identifier string) {
 	session.Values[identifierKey] = identifier
 }
+
+func SetLobby(session *sessions.Session, label string) {
+	session.Values[lobbyKey] = label
+}
+
+func ClearLobby(session *sessions.Session) {
+	delete(session.Values, lobbyKey)
+}
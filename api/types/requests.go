@@ -6,6 +6,7 @@ package types
 type JoinRequest struct {
 	Label    string `json:"label"`
 	Password string `json:"password"`
+	Token    string `json:"token,omitempty"`
 }
 
 // ---------------------------------------------------------------------------------------------------------------------
@@ -20,7 +21,8 @@ type PutSpotifySearchRequest struct {
 // queue controller
 
 type PutQueueVoteRequest struct {
-	TrackID string `json:"track"`
+	TrackID  string `json:"track"`
+	Downvote bool   `json:"downvote"`
 }
 
 type PutQueueCollectionRequest struct {
@@ -42,9 +44,23 @@ type DeleteQueueSongRequest struct {
 // jamsession controller
 
 type PutJamRequest struct {
-	Name     JSONString `json:"name,omitempty"`
-	Active   JSONBool   `json:"active,omitempty"`
-	Password JSONString `json:"password,omitempty"`
+	Name              JSONString   `json:"name,omitempty"`
+	Active            JSONBool     `json:"active,omitempty"`
+	Password          JSONString   `json:"password,omitempty"`
+	Ordering          JSONString   `json:"ordering,omitempty"`
+	DownvoteThreshold JSONInt      `json:"downvote_threshold,omitempty"`
+	SkipPercentage    JSONInt      `json:"skip_percentage,omitempty"`
+	AutoHandover      JSONBool     `json:"auto_handover,omitempty"`
+	RequireApproval   JSONBool     `json:"require_approval,omitempty"`
+	MaxPending        JSONInt      `json:"max_pending,omitempty"`
+	MaxAdditions      JSONInt      `json:"max_additions,omitempty"`
+	AdditionWindow    JSONInt      `json:"addition_window,omitempty"`
+	AdditionCooldown  JSONInt      `json:"addition_cooldown,omitempty"`
+	ReplayCooldown    JSONInt      `json:"replay_cooldown,omitempty"`
+	ReplaySongs       JSONInt      `json:"replay_songs,omitempty"`
+	LeadTime          JSONInt      `json:"lead_time,omitempty"`
+	Filter            *JamFilter   `json:"filter,omitempty"`
+	Fallback          *JamFallback `json:"fallback,omitempty"`
 }
 
 type PutPlaybackRequest struct {
@@ -62,6 +78,25 @@ type JamPlaySongRequest struct {
 	Remove  bool   `json:"remove"`
 }
 
+type PutJamHostRequest struct {
+	Identifier string `json:"identifier"`
+}
+
+type JamMemberIdentifierRequest struct {
+	Identifier string `json:"identifier"`
+}
+
+type PutJamKickRequest JamMemberIdentifierRequest
+type PutJamBanRequest JamMemberIdentifierRequest
+type DeleteJamBanRequest JamMemberIdentifierRequest
+type PutJamApproveRequest JamMemberIdentifierRequest
+type PutJamRejectRequest JamMemberIdentifierRequest
+
+type PutJamInviteRequest struct {
+	ExpiresIn JSONInt `json:"expires_in,omitempty"`
+	SingleUse bool    `json:"single_use"`
+}
+
 type PutJamMemberRequest JamMemberRequest
 type PutPlaySongRequest JamPlaySongRequest
 type PutJamJoinRequest JoinRequest
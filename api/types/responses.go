@@ -1,22 +1,57 @@
 package types
 
 import (
+	"time"
+
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
-	"github.com/zmb3/spotify/v2"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 )
 
 // ---------------------------------------------------------------------------------------------------------------------
 // general
 
 type JamResponse struct {
-	Label  string `json:"label"`
-	Name   string `json:"name"`
-	Active bool   `json:"active"`
+	Label             string      `json:"label"`
+	Name              string      `json:"name"`
+	Active            bool        `json:"active"`
+	Ordering          string      `json:"ordering"`
+	DownvoteThreshold int         `json:"downvote_threshold"`
+	SkipPercentage    int         `json:"skip_percentage"`
+	AutoHandover      bool        `json:"auto_handover"`
+	RequireApproval   bool        `json:"require_approval"`
+	MaxPending        int         `json:"max_pending"`
+	MaxAdditions      int         `json:"max_additions"`
+	AdditionWindow    int         `json:"addition_window"`
+	AdditionCooldown  int         `json:"addition_cooldown"`
+	ReplayCooldown    int         `json:"replay_cooldown"`
+	ReplaySongs       int         `json:"replay_songs"`
+	LeadTime          int         `json:"lead_time"`
+	Filter            JamFilter   `json:"filter"`
+	Fallback          JamFallback `json:"fallback"`
+}
+
+// JamFallback is the source of the songs played, when the queue runs dry. Type is playlist, album,
+// recommendations or empty, if no fallback songs are played
+type JamFallback struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+}
+
+// JamFilter restricts the tracks, which can be added to the queue. Durations are in seconds, 0 disables the limit
+type JamFilter struct {
+	BlockExplicit  bool     `json:"block_explicit"`
+	MinDuration    int      `json:"min_duration"`
+	MaxDuration    int      `json:"max_duration"`
+	BlockedArtists []string `json:"blocked_artists"`
+	BlockedTracks  []string `json:"blocked_tracks"`
+	AllowedGenres  []string `json:"allowed_genres"`
+	BlockedGenres  []string `json:"blocked_genres"`
 }
 
 type JamMember struct {
 	DisplayName string                  `json:"display_name,omitempty"`
 	Identifier  string                  `json:"identifier"`
+	Role        permissions.Role        `json:"role,omitempty"`
 	Permissions permissions.Permissions `json:"permissions"`
 }
 
@@ -25,8 +60,8 @@ type JamMemberResponse struct {
 }
 
 type PlaybackBody struct {
-	Playback *spotify.PlayerState `json:"playback"`
-	DeviceID spotify.ID           `json:"device_id"`
+	Playback *provider.PlayerState `json:"playback"`
+	DeviceID string                `json:"device_id"`
 }
 
 type LabelResponse struct {
@@ -66,7 +101,47 @@ type GetJamMembersResponse JamMemberResponse
 type PutJamMembersResponse JamMemberResponse
 
 type GetJamCreateResponse LabelResponse
-type PutJamJoinResponse LabelResponse
+
+type PutJamJoinResponse struct {
+	Label   string `json:"label"`
+	Pending bool   `json:"pending"`
+}
+
+type PutJamHostResponse JamMemberResponse
+type PutJamKickResponse JamMemberResponse
+type PutJamBanResponse JamMemberResponse
+
+type JamBan struct {
+	DisplayName string `json:"display_name,omitempty"`
+	Identifier  string `json:"identifier"`
+}
+
+type JamBanResponse struct {
+	Bans []JamBan `json:"bans"`
+}
+
+type GetJamBansResponse JamBanResponse
+type DeleteJamBanResponse JamBanResponse
+
+type JamPending struct {
+	DisplayName string `json:"display_name,omitempty"`
+	Identifier  string `json:"identifier"`
+}
+
+type JamLobbyResponse struct {
+	Pending []JamPending `json:"pending"`
+}
+
+type GetJamLobbyResponse JamLobbyResponse
+type PutJamApproveResponse JamLobbyResponse
+type PutJamRejectResponse JamLobbyResponse
+
+type PutJamInviteResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	SingleUse bool      `json:"single_use"`
+}
 
 type GetJamLeaveResponse struct {
 	Success bool `json:"success"`
@@ -75,14 +150,38 @@ type GetJamLeaveResponse struct {
 // ---------------------------------------------------------------------------------------------------------------------
 // queue controller
 
+// GetQueueResponse contains the queued songs. Fallback lists the next songs of the fallback source, if no songs
+// are queued
 type GetQueueResponse struct {
-	Tracks []Song `json:"tracks"`
+	Tracks   []Song            `json:"tracks"`
+	Quota    *QueueQuota       `json:"quota,omitempty"`
+	Fallback []*provider.Track `json:"fallback,omitempty"`
+}
+
+// QueueQuota is the remaining quota of a member. Pending and Additions are -1, if they aren't limited
+type QueueQuota struct {
+	Pending    int `json:"pending"`
+	Additions  int `json:"additions"`
+	RetryAfter int `json:"retry_after"`
 }
 
 type GetQueueHistoryResponse struct {
-	History []Song `json:"history"`
+	History []PlayedSong `json:"history"`
+	Total   int          `json:"total"`
+}
+
+type SkipProgress struct {
+	TrackID  string `json:"track"`
+	Votes    int    `json:"votes"`
+	Required int    `json:"required"`
+}
+
+type SkipResponse struct {
+	SkipProgress
+	Voted bool `json:"voted"`
 }
 
+type PutQueueSkipResponse SkipResponse
 type PutQueuePlaylistsResponse GetQueueResponse
 type PutQueueVoteResponse GetQueueResponse
 type DeleteQueueSongResponse GetQueueResponse
@@ -91,18 +190,19 @@ type DeleteQueueSongResponse GetQueueResponse
 // spotify controller
 
 type GetSpotifyPlaylistsResponse struct {
-	Playlists *spotify.SimplePlaylistPage `json:"playlists"`
+	Playlists *provider.Page[provider.Playlist] `json:"playlists"`
 }
 
 type GetSpotifyDevicesResponse struct {
-	Devices []spotify.PlayerDevice `json:"devices"`
+	Devices []provider.Device `json:"devices"`
 }
 
 type PutSpotifySearchResponse struct {
-	Artists   *spotify.FullArtistPage     `json:"artists"`
-	Albums    *spotify.SimpleAlbumPage    `json:"albums"`
-	Playlists *spotify.SimplePlaylistPage `json:"playlists"`
-	Tracks    *spotify.FullTrackPage      `json:"tracks"`
+	Artists   *provider.Page[provider.Artist]   `json:"artists"`
+	Albums    *provider.Page[provider.Album]    `json:"albums"`
+	Playlists *provider.Page[provider.Playlist] `json:"playlists"`
+	Tracks    *provider.Page[provider.Track]    `json:"tracks"`
+	Blocked   map[string]string                 `json:"blocked,omitempty"`
 }
 
 // ---------------------------------------------------------------------------------------------------------------------
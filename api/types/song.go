@@ -1,9 +1,24 @@
 package types
 
-import "github.com/zmb3/spotify/v2"
+import (
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+)
 
 type Song struct {
-	Song  *spotify.FullTrack `json:"spotifyTrackFull"`
-	Votes int                `json:"votes"`
-	Voted bool               `json:"voted"`
+	Song      *provider.Track `json:"spotifyTrackFull"`
+	Votes     int             `json:"votes"`
+	Voted     bool            `json:"voted"`
+	Downvotes int             `json:"downvotes"`
+	Downvoted bool            `json:"downvoted"`
+}
+
+type PlayedSong struct {
+	Song      *provider.Track `json:"spotifyTrackFull"`
+	Votes     int             `json:"votes"`
+	AddedBy   string          `json:"added_by"`
+	StartedAt time.Time       `json:"started_at"`
+	Skipped   bool            `json:"skipped"`
+	Fallback  bool            `json:"fallback"`
 }
@@ -4,3 +4,52 @@ type SocketJamMessage = JamResponse
 type SocketQueueMessage = GetQueueResponse
 type SocketPlaybackMessage = GetPlaybackResponse
 type SocketMemberMessage = GetJamMembersResponse
+type SocketSkipMessage = SkipProgress
+
+type SocketKickMessage struct {
+	Identifier string `json:"identifier"`
+	Banned     bool   `json:"banned"`
+}
+
+type SocketLobbyMessage = GetJamLobbyResponse
+
+type SocketApprovalMessage struct {
+	Approved bool `json:"approved"`
+}
+
+// SocketVoteCommand is the payload of the vote command
+type SocketVoteCommand struct {
+	TrackID  string `json:"track_id"`
+	Downvote bool   `json:"downvote"`
+}
+
+// SocketSubscribeCommand is the payload of the subscribe command. No events subscribe to all events
+type SocketSubscribeCommand struct {
+	Events []string `json:"events"`
+}
+
+type SocketPongMessage struct {
+	Time int64 `json:"time"`
+}
+
+type SocketSubscribedMessage struct {
+	Events []string `json:"events"`
+}
+
+// SocketErrorMessage is replied to a command, which failed. RetryAfter is set in seconds for rate limited commands
+type SocketErrorMessage struct {
+	Code       string `json:"code"`
+	Error      string `json:"error"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// SocketHelloMessage is sent to every client after connecting. Seq is the sequence number of the last event of the
+// stream. Resuming clients receive the events they missed or, if those aren't available anymore, a snapshot of the
+// current state as events.
+type SocketHelloMessage struct {
+	Stream   string        `json:"stream"`
+	Seq      uint64        `json:"seq"`
+	Resumed  bool          `json:"resumed"`
+	Missed   []interface{} `json:"missed,omitempty"`
+	Snapshot []interface{} `json:"snapshot,omitempty"`
+}
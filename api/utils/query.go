@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// QueryInt parses the query parameter key of r as int. If the parameter is missing, fallback is returned
+func QueryInt(r *http.Request, key string, fallback int) (int, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(val)
+}
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/authenticator"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/lease"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/presence"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/ratelimit"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/sqlstore"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
 	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
 	"path"
+	"syscall"
 	"time"
 
 	"github.com/jamfactoryapp/jamfactory-backend/api/sessions"
@@ -18,12 +29,16 @@ import (
 	"github.com/jamfactoryapp/jamfactory-backend/api/server"
 	pkgredis "github.com/jamfactoryapp/jamfactory-backend/internal/redis"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/cache"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/config"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamfactory"
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout is the time in-flight requests and websocket clients get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	rand.New(rand.NewSource(time.Now().UnixNano()))
 
@@ -41,6 +56,10 @@ func main() {
 
 	conf := config.New()
 
+	// ctx is done, when the backend is asked to stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Create JAM_DATA_DIR
 	if _, err := os.Stat(conf.DataDir); os.IsNotExist(err) {
 		if err := os.Mkdir(conf.DataDir, 0700); err != nil {
@@ -48,63 +67,151 @@ func main() {
 		}
 	}
 
-	// Create redis pool
-	pool, err := pkgredis.NewPool(conf.RedisAddress, conf.RedisPassword, conf.RedisDatabase)
-	if err != nil {
-		log.Fatal("could not connect to redis: ", err)
-	}
-	log.Debug("Initialized connection to redis")
-
 	authenticator := authenticator.NewAuthenticator(conf.SpotifyRedirectURL, conf.SpotifyID, conf.SpotifySecret)
 
-	// Create redis stores
-	redisStore := sessions.NewRedisSessionStore(pool, path.Join(conf.DataDir, ".keypairs"), conf.CookieSameSite, conf.CookieSecure)
-	log.Debug("Initialized session store")
+	// Select the music provider used for playback and search
+	realClock := clock.New()
+	var providers provider.Factory
+	switch conf.MusicProvider {
+	case config.MusicProviderFake:
+		providers = provider.NewFakeFactory(realClock)
+	default:
+		providers = provider.NewSpotifyFactory(authenticator)
+	}
+	log.Debug("Initialized music provider: ", conf.MusicProvider)
+
+	// Create stores for the configured backend
+	var sessionStore *sessions.Store
+	var userHubStores hub.Stores
+	var jamFactoryCache *cache.Cache
+	var stores jamfactory.Stores
+	// Without redis only a single instance can run, so JamSessions are coordinated in memory
+	cluster := jamsession.Cluster{
+		Broker:   notifications.NewMemoryBroker(),
+		Leases:   lease.NewMemory(),
+		Presence: presence.NewMemory(realClock),
+	}
+	var limiter ratelimit.Limiter = ratelimit.NewMemory(realClock)
+	keyPairsFile := path.Join(conf.DataDir, ".keypairs")
+
+	switch conf.StoreBackend {
+	case config.StoreBackendMemory:
+		sessionStore = sessions.NewMemorySessionStore(keyPairsFile, conf.CookieSameSite, conf.CookieSecure)
+		userHubStores = hub.Stores{
+			Store:       store.NewMemoryStore[users.UserInformation](),
+			Identifiers: store.NewMemorySet(),
+		}
+		jamFactoryCache = cache.NewMemory()
+		stores = jamfactory.Stores{
+			JamLabels: store.NewMemorySet(),
+			Settings:  store.NewMemoryStore[jamsession.Settings](),
+			Queues:    store.NewMemoryStore[queue.Queue](),
+			Members:   store.NewMemoryStore[jamsession.Members](),
+		}
+		log.Debug("Initialized memory stores")
+	case config.StoreBackendSQL:
+		db, err := sqlstore.Open(conf.SQLDriver, conf.SQLDSN)
+		if err != nil {
+			log.Fatal("could not open database: ", err)
+		}
+		log.Debug("Initialized connection to database")
+
+		sessionStore = sessions.NewStore(db.Sessions(), keyPairsFile, conf.CookieSameSite, conf.CookieSecure)
+		userHubStores = hub.Stores{
+			Store:       db.Users(),
+			Identifiers: db.Set("users"),
+		}
+		// Search results are only cached in memory, as they are cheap to recreate
+		jamFactoryCache = cache.NewMemory()
+		stores = jamfactory.Stores{
+			JamLabels: db.Set("jamSessions"),
+			Settings:  db.Settings(),
+			Queues:    db.Queues(),
+			Members:   db.Members(),
+		}
+		log.Debug("Initialized sql stores")
+	default:
+		// Create redis pool
+		pool, err := pkgredis.NewPool(conf.RedisAddress, conf.RedisPassword, conf.RedisDatabase)
+		if err != nil {
+			log.Fatal("could not connect to redis: ", err)
+		}
+		log.Debug("Initialized connection to redis")
 
-	userHubStores := hub.Stores{
-		Store:       store.NewRedisStore[users.UserInformation](pool, "user:info"),
-		Identifiers: store.NewRedisSet(pool, "users"),
+		sessionStore = sessions.NewRedisSessionStore(pool, keyPairsFile, conf.CookieSameSite, conf.CookieSecure)
+		userHubStores = hub.Stores{
+			Store:       store.NewRedisStore[users.UserInformation](pool, "user:info"),
+			Identifiers: store.NewRedisSet(pool, "users"),
+		}
+		jamFactoryCache = cache.NewRedis(pool)
+		stores = jamfactory.Stores{
+			JamLabels: store.NewRedisSet(pool, "jamSessions"),
+			Settings:  store.NewRedisStore[jamsession.Settings](pool, "jamSession:settings"),
+			Queues:    store.NewRedisStore[queue.Queue](pool, "jamSession:queue"),
+			Members:   store.NewRedisStore[jamsession.Members](pool, "jamSession:members"),
+		}
+		cluster = jamsession.Cluster{
+			Broker:   notifications.NewRedisBroker(pool),
+			Leases:   lease.NewRedis(pool, conf.InstanceID),
+			Presence: presence.NewRedis(pool, realClock),
+		}
+		limiter = ratelimit.NewRedis(pool)
+		log.Debug("Initialized redis stores")
 	}
-	userHub := hub.NewHub(authenticator, userHubStores)
-	log.Debug("Initialized user store")
 
-	// Create redis cache
-	redisCache := cache.NewRedis(pool)
-	log.Debug("Initialized redis cache")
+	userHub := hub.NewHub(providers, realClock, userHubStores)
+	log.Debug("Initialized user hub")
 
 	// Create JamFactory
-	stores := jamfactory.Stores{
-		JamLabels: store.NewRedisSet(pool, "jamSessions"),
-		Settings:  store.NewRedisStore[jamsession.Settings](pool, "jamSession:settings"),
-		Queues:    store.NewRedisStore[queue.Queue](pool, "jamSession:queue"),
-		Members:   store.NewRedisStore[jamsession.Members](pool, "jamSession:members"),
-	}
-	log.Debug("Initialized JamFactory store")
-	spotifyJamFactory := jamfactory.New(stores, userHub, redisCache)
+	spotifyJamFactory := jamfactory.New(ctx, stores, cluster, userHub, jamFactoryCache)
 	log.Info("Initialized JamFactory")
 
+	// Resume the JamSessions of a previous run
+	jamLabels, err := spotifyJamFactory.Resume()
+	if err != nil {
+		log.Warn("Could not resume JamSessions: ", err)
+	}
+	log.Info("Resumed ", len(jamLabels), " JamSessions")
+
 	// Create app server
-	appServer := server.NewServer("/", conf, redisStore, userHub, spotifyJamFactory, authenticator).
+	appServer := server.NewServer("/", conf, sessionStore, userHub, spotifyJamFactory, authenticator).
 		WithPort(conf.Port).
-		WithCache(redisCache)
-
-	if conf.UseHttps {
-		// Optionally create self-signed certificates for HTTPS
-		if conf.GenCerts {
-			server.GenCerts(conf.DNSNames, conf.CertFile, conf.KeyFile)
-		}
-
-		appServer = appServer.WithTLS(&tls.Config{
-			MinVersion: tls.VersionTLS13,
-		})
-		log.Infof("HTTPS server is listening on :%d\n", conf.Port)
-		if err := appServer.RunTLS(conf.CertFile, conf.KeyFile); err != nil {
-			log.Fatal("HTTPS server failed to listen: ", err)
+		WithCache(jamFactoryCache).
+		WithLimiter(limiter)
+
+	go func() {
+		var err error
+		if conf.UseHttps {
+			// Optionally create self-signed certificates for HTTPS
+			if conf.GenCerts {
+				server.GenCerts(conf.DNSNames, conf.CertFile, conf.KeyFile)
+			}
+
+			appServer = appServer.WithTLS(&tls.Config{
+				MinVersion: tls.VersionTLS13,
+			})
+			log.Infof("HTTPS server is listening on :%d\n", conf.Port)
+			err = appServer.RunTLS(conf.CertFile, conf.KeyFile)
+		} else {
+			log.Infof("HTTP server is listening on :%d\n", conf.Port)
+			err = appServer.Run()
 		}
-	} else {
-		log.Infof("HTTP server is listening on :%d\n", conf.Port)
-		if err := appServer.Run(); err != nil {
-			log.Fatal("HTTP server failed to listen: ", err)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed to listen: ", err)
 		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Info("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := appServer.Shutdown(shutdownCtx); err != nil {
+		log.Warn("Could not drain requests: ", err)
+	}
+	if err := spotifyJamFactory.Shutdown(shutdownCtx); err != nil {
+		log.Warn("Could not close JamSessions: ", err)
 	}
+	log.Info("Shutdown complete")
 }
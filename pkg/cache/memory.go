@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	pkgredis "github.com/jamfactoryapp/jamfactory-backend/internal/redis"
+)
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+type memoryBackend struct {
+	sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemory() *Cache {
+	memoryCache := &Cache{
+		backend:   &memoryBackend{entries: make(map[string]memoryEntry)},
+		keyPrefix: pkgredis.Key{}.Append(defaultKeyPrefix),
+		ttl:       defaultTTL,
+	}
+
+	return memoryCache
+}
+
+func (b *memoryBackend) get(key string) ([]byte, error) {
+	b.Lock()
+	defer b.Unlock()
+	entry, ok := b.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(b.entries, key)
+		return nil, nil
+	}
+	return entry.data, nil
+}
+
+func (b *memoryBackend) setex(key string, ttl int, data []byte) error {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	// Drop expired entries, so the cache doesn't grow without bounds
+	for k, entry := range b.entries {
+		if now.After(entry.expires) {
+			delete(b.entries, k)
+		}
+	}
+	b.entries[key] = memoryEntry{
+		data:    data,
+		expires: now.Add(time.Duration(ttl) * time.Second),
+	}
+	return nil
+}
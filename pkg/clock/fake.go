@@ -0,0 +1,144 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock, which only moves when it is advanced. Tickers and timers fire in order of their deadlines while
+// advancing, so a conductor can be stepped through a song deterministically.
+type Fake struct {
+	mutex   sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending timer, or a ticker if interval is set
+type fakeWaiter struct {
+	deadline time.Time
+	interval time.Duration
+	c        chan time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.changed = sync.NewCond(&f.mutex)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	w := &fakeWaiter{deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- f.now
+		return w.c
+	}
+	f.add(w)
+	return w.c
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	w := &fakeWaiter{deadline: f.now.Add(d), interval: d, c: make(chan time.Time, 1)}
+	f.add(w)
+	return &fakeTicker{clock: f, waiter: w}
+}
+
+// Advance moves the clock forward by d and fires the tickers and timers, which are due until then
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	until := f.now.Add(d)
+	for {
+		next := f.next(until)
+		if next == nil {
+			break
+		}
+		f.now = next.deadline
+		// Like time.Ticker, ticks are dropped for slow receivers
+		select {
+		case next.c <- f.now:
+		default:
+		}
+		if next.interval > 0 {
+			next.deadline = next.deadline.Add(next.interval)
+		} else {
+			f.remove(next)
+		}
+	}
+	f.now = until
+}
+
+// BlockUntil waits until n tickers and timers are pending, e.g. until a conductor started its ticker
+func (f *Fake) BlockUntil(n int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for len(f.waiters) < n {
+		f.changed.Wait()
+	}
+}
+
+// next returns the waiter with the earliest deadline not after until
+func (f *Fake) next(until time.Time) *fakeWaiter {
+	var next *fakeWaiter
+	for _, w := range f.waiters {
+		if !w.deadline.After(until) && (next == nil || w.deadline.Before(next.deadline)) {
+			next = w
+		}
+	}
+	return next
+}
+
+func (f *Fake) add(w *fakeWaiter) {
+	f.waiters = append(f.waiters, w)
+	f.changed.Broadcast()
+}
+
+func (f *Fake) remove(w *fakeWaiter) {
+	for i := range f.waiters {
+		if f.waiters[i] == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.changed.Broadcast()
+			return
+		}
+	}
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	waiter *fakeWaiter
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.waiter.c
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	t.waiter.interval = d
+	t.waiter.deadline = t.clock.now.Add(d)
+	t.clock.remove(t.waiter)
+	t.clock.add(t.waiter)
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	t.clock.remove(t.waiter)
+}
@@ -11,6 +11,18 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	MusicProviderSpotify = "spotify"
+	MusicProviderFake    = "fake"
+
+	StoreBackendRedis  = "redis"
+	StoreBackendMemory = "memory"
+	StoreBackendSQL    = "sql"
+
+	SQLDriverSQLite   = "sqlite"
+	SQLDriverPostgres = "postgres"
+)
+
 type Config struct {
 	Development        bool
 	UseHttps           bool
@@ -24,11 +36,17 @@ type Config struct {
 	SpotifyID          string
 	SpotifySecret      string
 	SpotifyRedirectURL string
+	MusicProvider      string
+	StoreBackend       string
+	SQLDriver          string
+	SQLDSN             string
 	RedisAddress       string
 	RedisDatabase      string
 	RedisPassword      string
+	InstanceID         string
 	CookieSameSite     http.SameSite
 	CookieSecure       bool
+	TrustProxy         bool
 }
 
 func New() *Config {
@@ -44,11 +62,16 @@ func New() *Config {
 		Port:            3000,
 		ClientAddresses: clientAddresses,
 		DataDir:         "./data",
+		MusicProvider:   MusicProviderSpotify,
+		StoreBackend:    StoreBackendRedis,
+		SQLDriver:       SQLDriverSQLite,
+		SQLDSN:          "./data/jamfactory.db",
 		RedisAddress:    "localhost:6379",
 		RedisDatabase:   "0",
 		RedisPassword:   "",
 		CookieSameSite:  http.SameSiteLaxMode,
 		CookieSecure:    true,
+		TrustProxy:      false,
 	}
 
 	// Set c.LogLevel
@@ -81,6 +104,7 @@ func New() *Config {
 		c.DataDir = dataDirVal
 		c.CertFile = path.Join(c.DataDir, "cert.pem")
 		c.KeyFile = path.Join(c.DataDir, "key.pem")
+		c.SQLDSN = path.Join(c.DataDir, "jamfactory.db")
 	} else {
 		log.Debug("JAM_DATA_DIR is empty. Using ", c.DataDir)
 	}
@@ -97,6 +121,18 @@ func New() *Config {
 		log.Debug("JAM_COOKIE_SECURE is empty. Using ", c.CookieSecure)
 	}
 
+	// Set c.TrustProxy
+	trustProxyVal := os.Getenv("JAM_TRUST_PROXY")
+	if trustProxyVal != "" {
+		trustProxy, err := strconv.ParseBool(trustProxyVal)
+		if err != nil {
+			log.Fatal("Failed to parse JAM_TRUST_PROXY: ", err)
+		}
+		c.TrustProxy = trustProxy
+	} else {
+		log.Debug("JAM_TRUST_PROXY is empty. Using ", c.TrustProxy)
+	}
+
 	// Set HTTPS related settings
 	useHttpsVal := os.Getenv("JAM_USE_HTTPS")
 	if useHttpsVal != "" {
@@ -174,6 +210,43 @@ func New() *Config {
 		log.Debug("JAM_CLIENT_ADDRESSES is empty. Using ", c.ClientAddresses)
 	}
 
+	// Set c.StoreBackend
+	storeBackendVal := strings.ToLower(os.Getenv("JAM_STORE_BACKEND"))
+	switch storeBackendVal {
+	case "":
+		log.Debug("JAM_STORE_BACKEND is empty. Using ", c.StoreBackend)
+	case StoreBackendRedis, StoreBackendMemory, StoreBackendSQL:
+		c.StoreBackend = storeBackendVal
+	default:
+		log.Fatal("Failed to parse JAM_STORE_BACKEND: unknown backend ", storeBackendVal)
+	}
+	if c.StoreBackend == StoreBackendMemory {
+		log.Warn("JAM FACTORY IS USING THE MEMORY STORE BACKEND! ALL DATA IS LOST ON RESTART!")
+	}
+
+	if c.StoreBackend == StoreBackendSQL {
+		// Set c.SQLDriver
+		sqlDriverVal := strings.ToLower(os.Getenv("JAM_SQL_DRIVER"))
+		switch sqlDriverVal {
+		case "":
+			log.Debug("JAM_SQL_DRIVER is empty. Using ", c.SQLDriver)
+		case SQLDriverSQLite, SQLDriverPostgres:
+			c.SQLDriver = sqlDriverVal
+		default:
+			log.Fatal("Failed to parse JAM_SQL_DRIVER: unknown driver ", sqlDriverVal)
+		}
+
+		// Set c.SQLDSN
+		sqlDSNVal := os.Getenv("JAM_SQL_DSN")
+		if sqlDSNVal != "" {
+			c.SQLDSN = sqlDSNVal
+		} else if c.SQLDriver == SQLDriverPostgres {
+			log.Fatal("JAM_SQL_DSN cannot be empty when JAM_SQL_DRIVER is postgres")
+		} else {
+			log.Debug("JAM_SQL_DSN is empty. Using ", c.SQLDSN)
+		}
+	}
+
 	// Set c.RedisAddress
 	redisAddressVal := os.Getenv("JAM_REDIS_ADDRESS")
 	if redisAddressVal != "" {
@@ -193,6 +266,30 @@ func New() *Config {
 	// Set c.RedisPassword
 	c.RedisPassword = os.Getenv("JAM_REDIS_PASSWORD")
 
+	// Set c.InstanceID
+	instanceIDVal := os.Getenv("JAM_INSTANCE_ID")
+	if instanceIDVal != "" {
+		c.InstanceID = instanceIDVal
+	} else {
+		hostname, _ := os.Hostname()
+		c.InstanceID = hostname + "-" + strconv.Itoa(os.Getpid())
+		log.Debug("JAM_INSTANCE_ID is empty. Using ", c.InstanceID)
+	}
+
+	// Set c.MusicProvider
+	musicProviderVal := strings.ToLower(os.Getenv("JAM_MUSIC_PROVIDER"))
+	switch musicProviderVal {
+	case "":
+		log.Debug("JAM_MUSIC_PROVIDER is empty. Using ", c.MusicProvider)
+	case MusicProviderSpotify, MusicProviderFake:
+		c.MusicProvider = musicProviderVal
+	default:
+		log.Fatal("Failed to parse JAM_MUSIC_PROVIDER: unknown provider ", musicProviderVal)
+	}
+	if c.MusicProvider == MusicProviderFake {
+		log.Warn("JAM FACTORY IS USING THE FAKE MUSIC PROVIDER!")
+	}
+
 	// Set c.Spotify* values
 	c.SpotifyID = os.Getenv("JAM_SPOTIFY_ID")
 	if c.SpotifyID == "" {
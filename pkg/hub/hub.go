@@ -3,22 +3,30 @@ package hub
 import (
 	"context"
 	"errors"
+	"sync"
 
-	"github.com/jamfactoryapp/jamfactory-backend/pkg/authenticator"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 )
 
 var (
 	ErrUserNotFound = errors.New("user not found")
 )
 
+// Hub holds the users of this backend instance. Their players and the conductors of their JamSessions measure time
+// with Clock.
 type Hub struct {
-	Authenticator *authenticator.Authenticator
+	Providers provider.Factory
+	Clock     clock.Clock
 	Stores
+	mutex sync.RWMutex
 	users map[string]*users.User
+	loads singleflight.Group
 }
 
 type Stores struct {
@@ -26,17 +34,18 @@ type Stores struct {
 	Identifiers store.Set
 }
 
-func NewHub(authenticator *authenticator.Authenticator, stores Stores) *Hub {
+func NewHub(providers provider.Factory, c clock.Clock, stores Stores) *Hub {
 	hub := &Hub{
-		Authenticator: authenticator,
-		Stores:        stores,
-		users:         make(map[string]*users.User),
+		Providers: providers,
+		Clock:     c,
+		Stores:    stores,
+		users:     make(map[string]*users.User),
 	}
 	return hub
 }
 
 func (h *Hub) NewUser(ctx context.Context, id string, username string, userType users.UserType, token *oauth2.Token) (*users.User, error) {
-	user, err := users.New(ctx, id, username, userType, h.Store, token, h.Authenticator)
+	user, err := users.New(ctx, id, username, userType, h.Store, token, h.Providers, h.Clock)
 	if err != nil {
 		return nil, err
 	}
@@ -44,37 +53,53 @@ func (h *Hub) NewUser(ctx context.Context, id string, username string, userType
 	if err != nil {
 		return nil, err
 	}
+	h.mutex.Lock()
 	h.users[id] = user
+	h.mutex.Unlock()
 	return user, nil
 }
 
 func (h *Hub) GetUserByIdentifier(ctx context.Context, identifier string) (*users.User, error) {
 	// Check if local user exists
-	user, ok := h.users[identifier]
-	if ok {
+	if user, ok := h.localUser(identifier); ok {
 		return user, nil
 	}
 	log.Trace("User not found local")
 
-	// Check if user identifier exists in store
-	exists, err := h.Identifiers.Has(identifier)
-	if err != nil {
-		return nil, err
-	}
+	// Concurrent requests for the same identifier share a single load
+	user, err, _ := h.loads.Do(identifier, func() (interface{}, error) {
+		if user, ok := h.localUser(identifier); ok {
+			return user, nil
+		}
 
-	if exists {
-		log.Trace("User found in store")
-		user = users.Load(ctx, identifier, h.Store, h.Authenticator)
-		h.users[identifier] = user
+		// Check if user identifier exists in store
+		exists, err := h.Identifiers.Has(identifier)
 		if err != nil {
 			return nil, err
 		}
+		if !exists {
+			log.Trace("User not found")
+			return nil, ErrUserNotFound
+		}
 
+		log.Trace("User found in store")
+		user := users.Load(ctx, identifier, h.Store, h.Providers, h.Clock)
+		h.mutex.Lock()
+		h.users[identifier] = user
+		h.mutex.Unlock()
 		return user, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	log.Trace("User not found")
-	return nil, ErrUserNotFound
+	return user.(*users.User), nil
+}
 
+func (h *Hub) localUser(identifier string) (*users.User, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	user, ok := h.users[identifier]
+	return user, ok
 }
 
 func (h *Hub) DeleteUser(ctx context.Context, identifier string) {
@@ -90,6 +115,8 @@ func (h *Hub) DeleteUser(ctx context.Context, identifier string) {
 
 	}
 
+	h.mutex.Lock()
 	delete(h.users, identifier)
+	h.mutex.Unlock()
 
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
@@ -18,7 +20,7 @@ import (
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
 	log "github.com/sirupsen/logrus"
-	"github.com/zmb3/spotify/v2"
+	"golang.org/x/sync/singleflight"
 )
 
 type Stores struct {
@@ -28,8 +30,16 @@ type Stores struct {
 	Queues    store.Store[queue.Queue]
 }
 
+var (
+	ErrClosed = errors.New("JamFactory is shutting down")
+)
+
 type JamFactory struct {
-	JamSessions map[string]*jamsession.JamSession
+	mutex       sync.RWMutex
+	jamSessions map[string]*jamsession.JamSession
+	closed      bool
+	loads       singleflight.Group
+	cluster     jamsession.Cluster
 	hub         *hub.Hub
 	cache       *cache.Cache
 	log         *log.Logger
@@ -37,37 +47,49 @@ type JamFactory struct {
 }
 
 const (
-	inactiveTime    = 2 * time.Hour
-	inactiveWarning = 1*time.Hour + 30*time.Minute
+	inactiveTime      = 2 * time.Hour
+	inactiveWarning   = 1*time.Hour + 30*time.Minute
+	reconcileInterval = 10 * time.Second
 )
 
-func New(stores Stores, hub *hub.Hub, ca *cache.Cache) *JamFactory {
+// New creates a JamFactory. Its background tasks run until ctx is done
+func New(ctx context.Context, stores Stores, cluster jamsession.Cluster, hub *hub.Hub, ca *cache.Cache) *JamFactory {
 	jamFactory := &JamFactory{
-		JamSessions: make(map[string]*jamsession.JamSession),
+		jamSessions: make(map[string]*jamsession.JamSession),
 		cache:       ca,
 		Stores:      stores,
+		cluster:     cluster,
 		hub:         hub,
 		log:         logutils.NewDefault(),
 	}
-	go jamFactory.Housekeeper()
+	go jamFactory.Housekeeper(ctx)
+	go jamFactory.Reconciler(ctx)
 	return jamFactory
 }
 
-func (s *JamFactory) Housekeeper() {
+func (s *JamFactory) Housekeeper(ctx context.Context) {
 	ticker := time.NewTicker(10 * time.Minute)
 	defer ticker.Stop()
 	for {
-		<-ticker.C
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 
-		for _, jamSession := range s.JamSessions {
-			if time.Now().After(jamSession.Timestamp.Add(inactiveWarning)) {
+		for _, jamSession := range s.JamSessions() {
+			// Only the instance conducting a JamSession closes it
+			if !jamSession.Conducting() {
+				continue
+			}
+			if s.hub.Clock.Now().After(jamSession.Timestamp().Add(inactiveWarning)) {
 				jamSession.NotifyClients(&notifications.Message{
 					Event:   notifications.Close,
 					Message: notifications.Warning,
 				})
 			}
 
-			if time.Now().After(jamSession.Timestamp.Add(inactiveTime)) {
+			if s.hub.Clock.Now().After(jamSession.Timestamp().Add(inactiveTime)) {
 				log.Debug(jamSession.JamLabel, ": inactive, closing")
 				jamSession.NotifyClients(&notifications.Message{
 					Event:   notifications.Close,
@@ -81,15 +103,118 @@ func (s *JamFactory) Housekeeper() {
 	}
 }
 
+// Reconciler keeps the local JamSessions in sync with the store, which is shared by all backend instances.
+// It takes over the JamSessions, which aren't conducted by any instance, so a conductor can fail over to any
+// instance, and unloads JamSessions deleted by other instances.
+func (s *JamFactory) Reconciler(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		jamLabels, err := s.Resume()
+		if err != nil {
+			s.log.Warn(err)
+			continue
+		}
+		exists := make(map[string]bool, len(jamLabels))
+		for _, jamLabel := range jamLabels {
+			exists[jamLabel] = true
+		}
+		for _, jamSession := range s.JamSessions() {
+			if exists[jamSession.JamLabel] {
+				continue
+			}
+			log.Debug(jamSession.JamLabel, ": deleted by another instance, unloading")
+			s.unload(jamSession)
+		}
+	}
+}
+
+// Resume loads the JamSessions in the store, whose lease this instance acquires, and returns the labels of all
+// JamSessions. JamSessions conducted by other instances are only loaded on demand, e.g. for the requests of their
+// members.
+func (s *JamFactory) Resume() ([]string, error) {
+	jamLabels, err := s.JamLabels.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, jamLabel := range jamLabels {
+		if _, ok := s.localJamSession(jamLabel); ok {
+			continue
+		}
+		held, err := s.cluster.Leases.Acquire(jamLabel)
+		if err != nil {
+			s.log.Warn(err)
+			continue
+		}
+		if !held {
+			continue
+		}
+		if _, err := s.GetJamSessionByLabel(jamLabel); err != nil {
+			s.log.Warn(err)
+			if err := s.cluster.Leases.Release(jamLabel); err != nil {
+				s.log.Warn(err)
+			}
+		}
+	}
+	return jamLabels, nil
+}
+
+// Shutdown closes all local JamSessions and notifies their clients about the restart.
+// The JamSessions stay in the store and are resumed by the remaining or restarted instances.
+func (s *JamFactory) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.closed = true
+	jamSessions := s.jamSessions
+	s.jamSessions = make(map[string]*jamsession.JamSession)
+	s.mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, jamSession := range jamSessions {
+		wg.Add(1)
+		go func(jamSession *jamsession.JamSession) {
+			defer wg.Done()
+			if err := jamSession.Shutdown(ctx, notifications.Restarting); err != nil {
+				s.log.Warn(jamSession.JamLabel, ": ", err)
+			}
+		}(jamSession)
+	}
+	wg.Wait()
+	log.Info("Closed ", len(jamSessions), " JamSessions")
+	return ctx.Err()
+}
+
+func (s *JamFactory) unload(jamSession *jamsession.JamSession) {
+	s.mutex.Lock()
+	current, ok := s.jamSessions[jamSession.JamLabel]
+	if ok && current == jamSession {
+		delete(s.jamSessions, jamSession.JamLabel)
+	}
+	s.mutex.Unlock()
+	if !ok || current != jamSession {
+		return
+	}
+	if err := jamSession.Deconstruct(); err != nil {
+		s.log.Warn(err)
+	}
+}
+
 func (s *JamFactory) DeleteJamSession(jamLabel string) error {
 	jamSession, err := s.GetJamSessionByLabel(jamLabel)
 	if err != nil {
 		return apierrors.ErrJamSessionNotFound
 	}
 
-	if err := jamSession.Deconstruct(); err != nil {
+	// Remove the label first, so the JamSession isn't loaded again by any instance
+	if err := s.Stores.JamLabels.Delete(jamLabel); err != nil {
 		return err
 	}
+	s.unload(jamSession)
 	if err := s.Stores.Members.Delete(jamLabel); err != nil {
 		return err
 	}
@@ -99,66 +224,111 @@ func (s *JamFactory) DeleteJamSession(jamLabel string) error {
 	if err := s.Stores.Queues.Delete(jamLabel); err != nil {
 		return err
 	}
-	if err := s.Stores.JamLabels.Delete(jamLabel); err != nil {
-		return err
-	}
-
-	delete(s.JamSessions, jamLabel)
 
 	return nil
 }
 
+// JamSessions returns a snapshot of the JamSessions loaded by this instance
+func (s *JamFactory) JamSessions() []*jamsession.JamSession {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	jamSessions := make([]*jamsession.JamSession, 0, len(s.jamSessions))
+	for _, jamSession := range s.jamSessions {
+		jamSessions = append(jamSessions, jamSession)
+	}
+	return jamSessions
+}
+
 func (s *JamFactory) GetJamSessionByLabel(jamLabel string) (*jamsession.JamSession, error) {
 	// Check if local JamSession exists
-	jamSession, ok := s.JamSessions[jamLabel]
-	if ok {
+	if jamSession, ok := s.localJamSession(jamLabel); ok {
 		return jamSession, nil
 	}
 	log.Trace("JamSession not found local")
 
-	// Check if label exists in store
-	exists, err := s.JamLabels.Has(jamLabel)
-	if err != nil {
-		return nil, err
-	}
+	// Concurrent requests for the same label share a single load, so only one conductor is started
+	jamSession, err, _ := s.loads.Do(jamLabel, func() (interface{}, error) {
+		if jamSession, ok := s.localJamSession(jamLabel); ok {
+			return jamSession, nil
+		}
+		if s.isClosed() {
+			return nil, ErrClosed
+		}
+
+		// Check if label exists in store
+		exists, err := s.JamLabels.Has(jamLabel)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			log.Trace("JamSession not found")
+			return nil, jamsession.ErrJamSessionMissing
+		}
 
-	if exists {
 		log.Trace("JamSession found in store")
 		stores := jamsession.Stores{
 			Members:  s.Members,
 			Queues:   s.Queues,
 			Settings: s.Settings,
 		}
-		jamSession, err = jamsession.Load(stores, s.hub, jamLabel)
-		s.JamSessions[jamLabel] = jamSession
+		jamSession, err := jamsession.Load(stores, s.cluster, s.hub, jamLabel)
 		if err != nil {
 			return nil, err
 		}
-
+		if err := s.register(jamSession); err != nil {
+			return nil, err
+		}
 		return jamSession, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jamSession.(*jamsession.JamSession), nil
+}
+
+// register adds jamSession to the local JamSessions. If the JamFactory was shut down in the meantime,
+// jamSession is closed again.
+func (s *JamFactory) register(jamSession *jamsession.JamSession) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		if err := jamSession.Deconstruct(); err != nil {
+			s.log.Warn(err)
+		}
+		return ErrClosed
 	}
-	log.Trace("JamSession not found")
-	return nil, jamsession.ErrJamSessionMissing
+	s.jamSessions[jamSession.JamLabel] = jamSession
+	s.mutex.Unlock()
+	return nil
+}
+
+func (s *JamFactory) isClosed() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.closed
+}
 
+func (s *JamFactory) localJamSession(jamLabel string) (*jamsession.JamSession, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	jamSession, ok := s.jamSessions[jamLabel]
+	return jamSession, ok
 }
 
+// GetJamSessionByUser returns the JamSession the user is a member of. Only this JamSession is loaded.
 func (s *JamFactory) GetJamSessionByUser(user *users.User) (*jamsession.JamSession, error) {
 	jamLabels, err := s.JamLabels.GetAll()
 	if err != nil {
 		log.Warn(err)
 	}
 	for _, jamLabel := range jamLabels {
-		jamSession, err := s.GetJamSessionByLabel(jamLabel)
+		members, err := s.Members.Get(jamLabel)
 		if err != nil {
 			log.Warn(err)
 			continue
 		}
-		members, err := jamSession.GetMembers()
-		if err != nil {
-			return nil, err
-		}
 		if _, err := members.Get(user.Identifier); err == nil {
-			return jamSession, nil
+			return s.GetJamSessionByLabel(jamLabel)
 		}
 	}
 	return nil, apierrors.ErrJamSessionNotFound
@@ -182,7 +352,7 @@ func (s *JamFactory) NewJamSession(host *users.User) (*jamsession.JamSession, er
 
 	jamLabel := s.CreateLabel(0)
 
-	jamSession, err := jamsession.CreateNew(host, stores, s.hub, jamLabel)
+	jamSession, err := jamsession.CreateNew(host, stores, s.cluster, s.hub, jamLabel)
 	if err != nil {
 		return nil, err
 	}
@@ -190,46 +360,65 @@ func (s *JamFactory) NewJamSession(host *users.User) (*jamsession.JamSession, er
 	if err != nil {
 		return nil, err
 	}
-	s.JamSessions[jamLabel] = jamSession
+	if err := s.register(jamSession); err != nil {
+		return nil, err
+	}
 	return jamSession, nil
 }
 
-func (s *JamFactory) Search(ctx context.Context, jamSession *jamsession.JamSession, searchType string, text string) (interface{}, error) {
-	country := spotify.CountryGermany
-	opts := []spotify.RequestOption{
-		spotify.Country(country),
-	}
+// SearchResult is the result of a search within a JamSession. Blocked contains the reasons, why tracks of the result
+// can't be queued because of the filter of the JamSession, by track ID.
+type SearchResult struct {
+	*provider.SearchResult
+	Blocked map[string]jamsession.FilterReason
+}
 
-	var spotifySearchType spotify.SearchType
+func (s *JamFactory) Search(ctx context.Context, jamSession *jamsession.JamSession, searchType string, text string) (*SearchResult, error) {
+	var providerSearchType provider.SearchType
 	var key = pkgredis.NewKey("search")
 	switch searchType {
 	case "track":
-		spotifySearchType = spotify.SearchTypeTrack
+		providerSearchType = provider.SearchTypeTrack
 		key = key.Append(searchType)
 	case "playlist":
-		spotifySearchType = spotify.SearchTypePlaylist
+		providerSearchType = provider.SearchTypePlaylist
 		key = key.Append(searchType)
 	case "album":
-		spotifySearchType = spotify.SearchTypeAlbum
+		providerSearchType = provider.SearchTypeAlbum
 		key = key.Append(searchType)
 	}
-	if spotifySearchType == 0 {
+	if providerSearchType == "" {
 		return nil, apierrors.ErrSearchTypeInvalid
 	}
 
 	searchString := []string{text, "*"}
 	entry, err := s.cache.Query(key, strings.Join(searchString, ""), func(index string) (interface{}, error) {
-		return jamSession.Search(ctx, index, spotifySearchType, opts...)
+		return jamSession.Search(ctx, index, providerSearchType)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	result, ok := entry.(*spotify.SearchResult)
+	result, ok := entry.(*provider.SearchResult)
 	if !ok {
 		return nil, apierrors.ErrSearchResultMalformed
 	}
 
-	return result, nil
+	// Search results are cached across JamSessions, so the filter is applied afterwards
+	var tracks []*provider.Track
+	if result.Tracks != nil {
+		for i := range result.Tracks.Items {
+			tracks = append(tracks, &result.Tracks.Items[i])
+		}
+	}
+	blocked, err := jamSession.BlockedTracks(ctx, tracks)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchResult{
+		SearchResult: result,
+		Blocked:      blocked,
+	}, nil
 }
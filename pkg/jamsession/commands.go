@@ -0,0 +1,109 @@
+package jamsession
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+)
+
+// handleCommand executes the websocket commands of the member identifier with the same permissions and rules as
+// the corresponding HTTP endpoints
+func (s *JamSession) handleCommand(ctx context.Context, identifier string, cmd *notifications.Command) (interface{}, error) {
+	members, err := s.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	// Members, who were kicked in the meantime, may still be connected
+	member, err := members.Get(identifier)
+	if err != nil || !member.Can(permissions.Vote) {
+		return nil, &notifications.CommandError{Code: notifications.CodeForbidden, Err: notifications.ErrCommandForbidden}
+	}
+
+	switch cmd.Type {
+	case notifications.CommandVote:
+		var payload types.SocketVoteCommand
+		if err := cmd.DecodePayload(&payload); err != nil {
+			return nil, err
+		}
+		if payload.TrackID == "" {
+			return nil, &notifications.CommandError{Code: notifications.CodeInvalid, Err: notifications.ErrCommandInvalid}
+		}
+		return s.voteCommand(ctx, member, payload)
+	case notifications.CommandSkipVote:
+		response, err := s.VoteSkip(ctx, identifier)
+		if err == ErrNothingPlaying {
+			return nil, &notifications.CommandError{Code: notifications.CodeInvalid, Err: err}
+		}
+		return response, err
+	default:
+		return nil, &notifications.CommandError{Code: notifications.CodeUnknown, Err: notifications.ErrCommandUnknown}
+	}
+}
+
+// voteCommand votes for a song like PUT /api/v1/queue/vote and returns the queue personalized for the member
+func (s *JamSession) voteCommand(ctx context.Context, member *Member, payload types.SocketVoteCommand) (*types.GetQueueResponse, error) {
+	currentQueue, err := s.GetQueue()
+	if err != nil {
+		return nil, err
+	}
+	// Voting for a song, which isn't queued yet, adds it to the queue
+	if !payload.Downvote && !currentQueue.Contains(payload.TrackID) && !member.Can(permissions.AddSong) {
+		return nil, &notifications.CommandError{Code: notifications.CodeForbidden, Err: notifications.ErrCommandForbidden}
+	}
+
+	if payload.Downvote {
+		err = s.Downvote(payload.TrackID, member.Identifier)
+	} else {
+		err = s.Vote(ctx, payload.TrackID, member.Identifier)
+	}
+	var quotaErr *queue.QuotaError
+	switch {
+	case err == nil:
+	case errors.As(err, &quotaErr):
+		return nil, &notifications.CommandError{Code: notifications.CodeRateLimited, Err: quotaErr, RetryAfter: quotaErr.RetryAfter}
+	case errors.Is(err, ErrTrackBlocked), errors.Is(err, queue.ErrRecentlyPlayed):
+		return nil, &notifications.CommandError{Code: notifications.CodeForbidden, Err: err}
+	case errors.Is(err, queue.ErrSongNotFound):
+		return nil, &notifications.CommandError{Code: notifications.CodeNotFound, Err: err}
+	default:
+		return nil, err
+	}
+
+	currentQueue, err = s.GetQueue()
+	if err != nil {
+		return nil, err
+	}
+	quota, err := s.RemainingQuota(currentQueue, member.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	return &types.GetQueueResponse{
+		Tracks:   currentQueue.For(member.Identifier),
+		Quota:    quota,
+		Fallback: currentQueue.UpcomingFallback(),
+	}, nil
+}
+
+// RemainingQuota returns how many songs voteID can still add to the queue, or nil for the host, who isn't limited
+func (s *JamSession) RemainingQuota(q *queue.Queue, voteID string) (*types.QueueQuota, error) {
+	members, err := s.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	host, err := members.Host()
+	if err != nil {
+		return nil, err
+	}
+	if host.Identifier == voteID {
+		return nil, nil
+	}
+	settings, err := s.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	return q.RemainingQuota(voteID, settings.Quota, s.clock.Now()), nil
+}
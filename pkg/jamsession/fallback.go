@@ -0,0 +1,205 @@
+package jamsession
+
+import (
+	"context"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// recommendationSeeds is the number of recently played songs the recommendations are based on
+	recommendationSeeds = 5
+	// recommendationLimit is the number of recommended songs loaded at once
+	recommendationLimit = 20
+	// fallbackReloadInterval is the minimum time between two loads of the fallback source, so a source without
+	// playable songs doesn't cause a request every second
+	fallbackReloadInterval = time.Minute
+)
+
+// FallbackType is the kind of source the songs are taken from, when the queue runs dry
+type FallbackType string
+
+const (
+	FallbackNone            FallbackType = ""
+	FallbackPlaylist        FallbackType = "playlist"
+	FallbackAlbum           FallbackType = "album"
+	FallbackRecommendations FallbackType = "recommendations"
+)
+
+// Fallback is the source of the songs played, when no songs are queued. ID is the ID of the playlist or album.
+// Recommendations are based on the songs played recently.
+type Fallback struct {
+	Type FallbackType
+	ID   string
+}
+
+// NewFallback creates a Fallback from its API representation
+func NewFallback(fallback types.JamFallback) Fallback {
+	return Fallback{
+		Type: FallbackType(fallback.Type),
+		ID:   fallback.ID,
+	}
+}
+
+// Response returns the API representation of the fallback
+func (f Fallback) Response() types.JamFallback {
+	return types.JamFallback{
+		Type: string(f.Type),
+		ID:   f.ID,
+	}
+}
+
+// Valid reports whether the type is known and playlists and albums have an ID
+func (f Fallback) Valid() bool {
+	switch f.Type {
+	case FallbackNone, FallbackRecommendations:
+		return true
+	case FallbackPlaylist, FallbackAlbum:
+		return f.ID != ""
+	default:
+		return false
+	}
+}
+
+// ResetFallback drops the loaded songs of the fallback source, so the songs of a new source are played next
+func (s *JamSession) ResetFallback() error {
+	s.fallbackLoaded.Store(0)
+	err := s.UpdateQueue(func(q *queue.Queue) error {
+		q.Fallback = nil
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.SocketQueueUpdate()
+	return nil
+}
+
+// playFallback plays the next song of the fallback source on the device of the host
+func (s *JamSession) playFallback(ctx context.Context, settings *Settings, host *users.User) error {
+	track, err := s.peekFallback(ctx, settings, host)
+	if err != nil || track == nil {
+		return err
+	}
+	return s.play(ctx, track, false, true)
+}
+
+// Start plays the next song of the queue or, if the queue is empty, of the fallback source on the device of the host.
+// queue.ErrQueueEmpty is returned, if there is no song to play.
+func (s *JamSession) Start(ctx context.Context, settings *Settings, host *users.User) error {
+	currentQueue, err := s.GetQueue()
+	if err != nil {
+		return err
+	}
+	return s.playNext(ctx, currentQueue, settings, host)
+}
+
+// playNext plays the next song of q or, if q is empty, of the fallback source. queue.ErrQueueEmpty is returned, if
+// there is no song to play.
+func (s *JamSession) playNext(ctx context.Context, q *queue.Queue, settings *Settings, host *users.User) error {
+	next, err := q.GetNext()
+	if err == nil {
+		return s.Play(ctx, next.Track, true)
+	}
+	if err != queue.ErrQueueEmpty {
+		return err
+	}
+	track, err := s.peekFallback(ctx, settings, host)
+	if err != nil {
+		return err
+	}
+	if track == nil {
+		return queue.ErrQueueEmpty
+	}
+	return s.play(ctx, track, false, true)
+}
+
+// peekFallback returns the next song of the fallback source without taking it from the queue, so it stays next until
+// the device of the host accepted it. The songs of the source are loaded again, once all of them were played. nil is
+// returned, if there is no fallback song or songs are queued.
+func (s *JamSession) peekFallback(ctx context.Context, settings *Settings, host *users.User) (*provider.Track, error) {
+	if settings.Fallback.Type == FallbackNone {
+		return nil, nil
+	}
+	currentQueue, err := s.GetQueue()
+	if err != nil {
+		return nil, err
+	}
+	if len(currentQueue.Fallback) == 0 {
+		if s.clock.Now().Sub(time.Unix(0, s.fallbackLoaded.Load())) < fallbackReloadInterval {
+			return nil, nil
+		}
+		s.fallbackLoaded.Store(s.clock.Now().UnixNano())
+		tracks, err := s.loadFallback(ctx, settings, host.Provider(), currentQueue.History)
+		if err != nil {
+			return nil, err
+		}
+		log.WithField("Label", s.JamLabel).Debug("Loaded ", len(tracks), " fallback songs")
+		err = s.UpdateQueue(func(q *queue.Queue) error {
+			if len(q.Fallback) == 0 {
+				q.Fallback = tracks
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var track *provider.Track
+	err = s.UpdateQueue(func(q *queue.Queue) error {
+		// Songs queued in the meantime take precedence
+		if len(q.Songs) > 0 {
+			track = nil
+			return nil
+		}
+		track = q.PeekFallback(settings.Replay, s.clock.Now())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// loadFallback returns the songs of the fallback source, which aren't blocked by the filter of the JamSession
+func (s *JamSession) loadFallback(ctx context.Context, settings *Settings, musicProvider provider.MusicProvider, history []*queue.PlayedSong) ([]*provider.Track, error) {
+	var tracks []*provider.Track
+	var err error
+	switch settings.Fallback.Type {
+	case FallbackPlaylist:
+		tracks, err = musicProvider.PlaylistTracks(ctx, settings.Fallback.ID)
+	case FallbackAlbum:
+		tracks, err = musicProvider.AlbumTracks(ctx, settings.Fallback.ID)
+	case FallbackRecommendations:
+		var seeds []string
+		for i := len(history) - 1; i >= 0 && len(seeds) < recommendationSeeds; i-- {
+			seeds = append(seeds, history[i].ID)
+		}
+		// Recommendations need something to start from
+		if len(seeds) == 0 {
+			return nil, nil
+		}
+		tracks, err = musicProvider.Recommendations(ctx, seeds, recommendationLimit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := blockedTracks(ctx, settings.Filter, musicProvider, tracks)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]*provider.Track, 0, len(tracks))
+	for _, track := range tracks {
+		if _, ok := blocked[track.ID]; !ok {
+			allowed = append(allowed, track)
+		}
+	}
+	return allowed, nil
+}
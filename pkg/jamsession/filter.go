@@ -0,0 +1,235 @@
+package jamsession
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+)
+
+// MaxFilterEntries is the maximum number of entries of each list of a Filter
+const MaxFilterEntries = 200
+
+var (
+	ErrTrackBlocked = errors.New("track is blocked by the filter of the JamSession")
+)
+
+// Filter restricts the tracks, which can be added to the queue of a JamSession. Durations of 0 and empty lists don't
+// restrict anything. Artists and tracks are blocked by ID. Genres match, if they are part of a genre of an artist of
+// the track. If AllowedGenres isn't empty, tracks have to match one of them.
+type Filter struct {
+	BlockExplicit  bool
+	MinDuration    time.Duration
+	MaxDuration    time.Duration
+	BlockedArtists []string
+	BlockedTracks  []string
+	AllowedGenres  []string
+	BlockedGenres  []string
+}
+
+// FilterReason is the reason, why a Filter blocks a track
+type FilterReason string
+
+const (
+	FilterBlockedTrack  FilterReason = "blocked_track"
+	FilterBlockedArtist FilterReason = "blocked_artist"
+	FilterExplicit      FilterReason = "explicit"
+	FilterTooShort      FilterReason = "too_short"
+	FilterTooLong       FilterReason = "too_long"
+	FilterGenre         FilterReason = "genre"
+)
+
+// FilterError is returned, if a track can't be queued because of the Filter of the JamSession
+type FilterError struct {
+	Reason FilterReason
+}
+
+func (e *FilterError) Error() string {
+	return ErrTrackBlocked.Error() + ": " + string(e.Reason)
+}
+
+func (e *FilterError) Unwrap() error {
+	return ErrTrackBlocked
+}
+
+// NewFilter creates a Filter from its API representation
+func NewFilter(filter types.JamFilter) Filter {
+	return Filter{
+		BlockExplicit:  filter.BlockExplicit,
+		MinDuration:    time.Duration(filter.MinDuration) * time.Second,
+		MaxDuration:    time.Duration(filter.MaxDuration) * time.Second,
+		BlockedArtists: normalizeEntries(filter.BlockedArtists, false),
+		BlockedTracks:  normalizeEntries(filter.BlockedTracks, false),
+		AllowedGenres:  normalizeEntries(filter.AllowedGenres, true),
+		BlockedGenres:  normalizeEntries(filter.BlockedGenres, true),
+	}
+}
+
+// Response returns the API representation of the filter
+func (f Filter) Response() types.JamFilter {
+	return types.JamFilter{
+		BlockExplicit:  f.BlockExplicit,
+		MinDuration:    int(f.MinDuration.Seconds()),
+		MaxDuration:    int(f.MaxDuration.Seconds()),
+		BlockedArtists: append([]string{}, f.BlockedArtists...),
+		BlockedTracks:  append([]string{}, f.BlockedTracks...),
+		AllowedGenres:  append([]string{}, f.AllowedGenres...),
+		BlockedGenres:  append([]string{}, f.BlockedGenres...),
+	}
+}
+
+// Valid reports whether the durations and the sizes of the lists of the filter are allowed
+func (f Filter) Valid() bool {
+	if f.MinDuration < 0 || f.MaxDuration < 0 || (f.MaxDuration > 0 && f.MaxDuration < f.MinDuration) {
+		return false
+	}
+	for _, entries := range [][]string{f.BlockedArtists, f.BlockedTracks, f.AllowedGenres, f.BlockedGenres} {
+		if len(entries) > MaxFilterEntries {
+			return false
+		}
+	}
+	return true
+}
+
+// Active reports whether the filter restricts any tracks
+func (f Filter) Active() bool {
+	return f.BlockExplicit || f.MinDuration > 0 || f.MaxDuration > 0 || len(f.BlockedArtists) > 0 ||
+		len(f.BlockedTracks) > 0 || f.usesGenres()
+}
+
+func (f Filter) usesGenres() bool {
+	return len(f.AllowedGenres) > 0 || len(f.BlockedGenres) > 0
+}
+
+// Check returns the reason, why the filter blocks track, or an empty reason if the track is allowed.
+// genres contains the genres of the artists of the track by artist ID.
+func (f Filter) Check(track *provider.Track, genres map[string][]string) FilterReason {
+	if contains(f.BlockedTracks, track.ID) {
+		return FilterBlockedTrack
+	}
+	for _, artist := range track.Artists {
+		if contains(f.BlockedArtists, artist.ID) {
+			return FilterBlockedArtist
+		}
+	}
+	if f.BlockExplicit && track.Explicit {
+		return FilterExplicit
+	}
+	duration := time.Duration(track.Duration) * time.Millisecond
+	if f.MinDuration > 0 && duration < f.MinDuration {
+		return FilterTooShort
+	}
+	if f.MaxDuration > 0 && duration > f.MaxDuration {
+		return FilterTooLong
+	}
+	if f.usesGenres() {
+		var trackGenres []string
+		for _, artist := range track.Artists {
+			trackGenres = append(trackGenres, genres[artist.ID]...)
+		}
+		if matchesGenre(trackGenres, f.BlockedGenres) {
+			return FilterGenre
+		}
+		// Tracks without known genres can't match an allowed genre
+		if len(f.AllowedGenres) > 0 && !matchesGenre(trackGenres, f.AllowedGenres) {
+			return FilterGenre
+		}
+	}
+	return ""
+}
+
+// BlockedTracks returns the reasons, why tracks are blocked by the filter of the JamSession, by track ID
+func (s *JamSession) BlockedTracks(ctx context.Context, tracks []*provider.Track) (map[string]FilterReason, error) {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Filter.Active() {
+		return map[string]FilterReason{}, nil
+	}
+	members, err := s.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	hostMember, err := members.Host()
+	if err != nil {
+		return nil, err
+	}
+	host, err := s.hub.GetUserByIdentifier(ctx, hostMember.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	return blockedTracks(ctx, settings.Filter, host.Provider(), tracks)
+}
+
+// blockedTracks checks tracks with filter and looks up the genres of their artists with musicProvider, if needed
+func blockedTracks(ctx context.Context, filter Filter, musicProvider provider.MusicProvider, tracks []*provider.Track) (map[string]FilterReason, error) {
+	blocked := make(map[string]FilterReason)
+	if !filter.Active() {
+		return blocked, nil
+	}
+	var genres map[string][]string
+	if filter.usesGenres() {
+		var artistIDs []string
+		seen := make(map[string]struct{})
+		for _, track := range tracks {
+			for _, artist := range track.Artists {
+				if _, ok := seen[artist.ID]; !ok && artist.ID != "" {
+					seen[artist.ID] = struct{}{}
+					artistIDs = append(artistIDs, artist.ID)
+				}
+			}
+		}
+		var err error
+		genres, err = musicProvider.ArtistGenres(ctx, artistIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, track := range tracks {
+		if reason := filter.Check(track, genres); reason != "" {
+			blocked[track.ID] = reason
+		}
+	}
+	return blocked, nil
+}
+
+func matchesGenre(genres []string, patterns []string) bool {
+	for _, genre := range genres {
+		genre = strings.ToLower(genre)
+		for _, pattern := range patterns {
+			if strings.Contains(genre, pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalizeEntries trims the entries of a filter list and drops empty and duplicate entries.
+// Genres are compared in lower case.
+func normalizeEntries(entries []string, lower bool) []string {
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if lower {
+			entry = strings.ToLower(entry)
+		}
+		if entry != "" && !contains(normalized, entry) {
+			normalized = append(normalized, entry)
+		}
+	}
+	return normalized
+}
+
+func contains(entries []string, entry string) bool {
+	for _, e := range entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package jamsession
+
+import (
+	"context"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultLeadTime is used, if no lead time was set
+	DefaultLeadTime = 5 * time.Second
+	MinLeadTime     = time.Second
+	MaxLeadTime     = 30 * time.Second
+	// syncLeadTime is the time before the handoff, from which on the player state is updated every second
+	syncLeadTime = 5 * time.Second
+)
+
+// handoff is the song handed over to the device of the host, to be played after the song started at from
+type handoff struct {
+	from     time.Time
+	track    *provider.Track
+	fallback bool
+}
+
+// EffectiveLeadTime returns the time before the predicted end of a song, at which the next song is handed over to
+// the device of the host. It needs to be longer than the crossfade of the device.
+func (settings *Settings) EffectiveLeadTime() time.Duration {
+	if settings.LeadTime <= 0 {
+		return DefaultLeadTime
+	}
+	return settings.LeadTime
+}
+
+// handOver enqueues the next song on the device of the host, so the device plays it right after the current song
+// without a gap. Every song is only followed by one handoff. A song of the fallback source is only taken from the
+// queue, once the device accepted it. Otherwise the handoff is tried again on the next tick, and if the device can't
+// enqueue songs at all, the next song is played once the current one ended.
+func (s *JamSession) handOver(ctx context.Context, settings *Settings, host *users.User) error {
+	currentQueue, err := s.GetQueue()
+	if err != nil {
+		return err
+	}
+	var from time.Time
+	if len(currentQueue.History) > 0 {
+		from = currentQueue.History[len(currentQueue.History)-1].StartedAt
+	}
+	if s.handoff != nil && s.handoff.from.Equal(from) {
+		return nil
+	}
+
+	next := &handoff{from: from}
+	so, err := currentQueue.GetNext()
+	switch err {
+	case nil:
+		next.track = so.Track
+	case queue.ErrQueueEmpty:
+		next.track, err = s.peekFallback(ctx, settings, host)
+		if err != nil {
+			return err
+		}
+		next.fallback = true
+	default:
+		return err
+	}
+	if next.track == nil {
+		return nil
+	}
+
+	if err := host.Enqueue(ctx, next.track); err != nil {
+		log.WithField("Label", s.JamLabel).Debug("Could not hand over the next song: ", err)
+		return nil
+	}
+	s.handoff = next
+	log.WithField("Label", s.JamLabel).Debug("Handed over ", next.track.ID)
+	if !next.fallback {
+		return nil
+	}
+	return s.UpdateQueue(func(q *queue.Queue) error {
+		q.RemoveFallback(next.track.ID)
+		return nil
+	})
+}
+
+// handOverScheduled hands the next song over at the time scheduled by the conductor
+func (s *JamSession) handOverScheduled(ctx context.Context) error {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return err
+	}
+	if !settings.Active || !s.Conducting() {
+		return nil
+	}
+	members, err := s.GetMembers()
+	if err != nil {
+		return err
+	}
+	hostMember, err := members.Host()
+	if err != nil {
+		return err
+	}
+	host, err := s.hub.GetUserByIdentifier(ctx, hostMember.Identifier)
+	if err != nil {
+		return err
+	}
+	return s.handOver(ctx, settings, host)
+}
+
+// recordHandoff records track, which the device of the host started after the handoff, in the history and removes
+// it from the queue
+func (s *JamSession) recordHandoff(host *users.User, track *provider.Track) error {
+	startedAt := s.clock.Now().Add(-time.Duration(host.GetPlayerState().Progress) * time.Millisecond)
+	fallback := s.handoff != nil && s.handoff.track.ID == track.ID && s.handoff.fallback
+	err := s.UpdateQueue(func(q *queue.Queue) error {
+		q.Advance(track, startedAt).Fallback = fallback
+		// A crossfade starts the next song before the end of the previous one, which isn't a skip
+		if len(q.History) > 1 {
+			q.History[len(q.History)-2].Skipped = false
+		}
+		q.Delete(track.ID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.SocketQueueUpdate()
+	return nil
+}
@@ -0,0 +1,155 @@
+package jamsession
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+)
+
+func TestHandOverFallback(t *testing.T) {
+	j := newTestJam(t)
+	ctx := context.Background()
+	j.update(t, func(settings *Settings) {
+		settings.Fallback = Fallback{Type: FallbackPlaylist, ID: "fake-playlist"}
+	})
+	settings, err := j.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The device goes offline, so the handoff fails
+	j.device.SetDeviceActive(false)
+	if err := j.handOver(ctx, settings, j.host); err != nil {
+		t.Fatal(err)
+	}
+	if j.handoff != nil {
+		t.Errorf("handoff of %s recorded, although the device rejected it", j.handoff.track.ID)
+	}
+	if fallback := j.queue(t).Fallback; len(fallback) == 0 || fallback[0].ID != "fake-track-00" {
+		t.Fatal("the rejected fallback song was taken from the queue")
+	}
+
+	// The next attempt succeeds and takes the song from the fallback source
+	j.device.SetDeviceActive(true)
+	if err := j.handOver(ctx, settings, j.host); err != nil {
+		t.Fatal(err)
+	}
+	if j.handoff == nil || j.handoff.track.ID != "fake-track-00" || !j.handoff.fallback {
+		t.Fatalf("handoff = %+v, want fallback song fake-track-00", j.handoff)
+	}
+	fallback := j.queue(t).Fallback
+	if len(fallback) == 0 || fallback[0].ID != "fake-track-01" {
+		t.Error("the handed over fallback song is still next")
+	}
+
+	// Every song is only followed by one handoff
+	if err := j.handOver(ctx, settings, j.host); err != nil {
+		t.Fatal(err)
+	}
+	if len(j.queue(t).Fallback) != len(fallback) {
+		t.Error("a second fallback song was handed over for the same song")
+	}
+}
+
+func TestHandoffTimer(t *testing.T) {
+	j := newTestJam(t)
+	ctx := context.Background()
+	current := j.track(t, "fake-track-00")
+	next := j.track(t, "fake-track-01")
+	j.update(t, func(settings *Settings) {
+		settings.Active = true
+		settings.LeadTime = DefaultLeadTime
+	})
+
+	// Start the song between two ticks, so the handoff is due between two ticks as well
+	j.tick(2)
+	j.clock.advance(500 * time.Millisecond)
+	if err := j.Play(ctx, current, false); err != nil {
+		t.Fatal(err)
+	}
+	end := j.clock.Now().Add(songDuration(current))
+	j.enqueue(t, "member", next)
+	j.clock.advance(500 * time.Millisecond)
+
+	j.tickUntil(t, 200, func() bool {
+		return end.Sub(j.clock.Now()) < DefaultLeadTime+time.Second
+	})
+	if j.handoff != nil {
+		t.Fatalf("%s handed over %v before the end, want %v", j.handoff.track.ID, end.Sub(j.clock.Now()), DefaultLeadTime)
+	}
+
+	// The timer fires before the next tick
+	j.clock.advance(end.Sub(j.clock.Now()) - DefaultLeadTime)
+	if j.handoff == nil || j.handoff.track.ID != next.ID {
+		t.Fatalf("handoff = %+v at %v before the end, want %s", j.handoff, end.Sub(j.clock.Now()), next.ID)
+	}
+
+	// The device continues with the handed over song without a gap
+	j.clock.advance(DefaultLeadTime + time.Second)
+	if playing := j.playing(t); playing == nil || playing.ID != next.ID {
+		t.Errorf("device plays %v after the end, want %s", playing, next.ID)
+	}
+}
+
+func TestRecordHandoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback bool
+	}{
+		{"queued", false},
+		{"fallback", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newTestJam(t)
+			previous := j.track(t, "fake-track-00")
+			next := j.track(t, "fake-track-01")
+			err := j.UpdateQueue(func(q *queue.Queue) error {
+				q.Advance(previous, epoch)
+				if tt.fallback {
+					return nil
+				}
+				return q.Vote(next.ID, "member", next)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			j.handoff = &handoff{from: epoch, track: next, fallback: tt.fallback}
+
+			// A crossfade of 8s started the next song before the end of the previous one
+			startedAt := epoch.Add(songDuration(previous) - 8*time.Second)
+			j.clock.advance(startedAt.Add(2 * time.Second).Sub(j.clock.Now()))
+			j.host.SetPlayerState(&provider.PlayerState{Item: next, Progress: 2000, Playing: true})
+			if err := j.recordHandoff(j.host, next); err != nil {
+				t.Fatal(err)
+			}
+
+			q := j.queue(t)
+			if len(q.History) != 2 {
+				t.Fatalf("history has %d songs, want 2", len(q.History))
+			}
+			if q.History[0].Skipped {
+				t.Error("the crossfaded song was recorded as skipped")
+			}
+			played := q.History[1]
+			if played.ID != next.ID {
+				t.Errorf("recorded %s, want %s", played.ID, next.ID)
+			}
+			if !played.StartedAt.Equal(startedAt) {
+				t.Errorf("recorded start %v, want %v", played.StartedAt, startedAt)
+			}
+			if played.Fallback != tt.fallback {
+				t.Errorf("recorded fallback %t, want %t", played.Fallback, tt.fallback)
+			}
+			if q.Contains(next.ID) {
+				t.Error("the handed over song is still queued")
+			}
+			if !tt.fallback && played.Submitter != "member" {
+				t.Errorf("recorded submitter %q, want %q", played.Submitter, "member")
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package jamsession
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrHostNotSpotify      = errors.New("host must be authorized by Spotify")
+	ErrNoHandoverCandidate = errors.New("no member can become host")
+	ErrAlreadyHost         = errors.New("member already is the host")
+)
+
+// TransferHost makes the member identifier the host of the JamSession. The previous host becomes a co-host,
+// or is removed from the JamSession if leave is set. From then on the conductor controls the playback of the new host.
+func (s *JamSession) TransferHost(ctx context.Context, identifier string, leave bool) (*Members, error) {
+	newHost, err := s.hub.GetUserByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if !spotifyUser(newHost) {
+		return nil, ErrHostNotSpotify
+	}
+
+	var previousHost string
+	var members *Members
+	err = s.UpdateMembers(func(current *Members) error {
+		member, err := current.Get(identifier)
+		if err != nil {
+			return err
+		}
+		host, err := current.Host()
+		if err != nil {
+			return err
+		}
+		if host.Identifier == identifier {
+			return ErrAlreadyHost
+		}
+		member.SetPermissions(permissions.RoleHost.Permissions()...)
+		if leave {
+			current.Remove(host.Identifier)
+		} else {
+			host.SetPermissions(permissions.RoleCoHost.Permissions()...)
+		}
+		previousHost = host.Identifier
+		members = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Info("Transferred host to ", identifier)
+
+	s.handoverPlayback(ctx, previousHost, newHost)
+	return members, nil
+}
+
+// Handover transfers the host to the member with the most permissions, which is authorized by Spotify, and removes
+// the previous host from the JamSession
+func (s *JamSession) Handover(ctx context.Context) (*Members, error) {
+	members, err := s.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]*Member, 0)
+	for _, member := range *members {
+		if member.HasPermissions(permissions.Host) {
+			continue
+		}
+		user, err := s.hub.GetUserByIdentifier(ctx, member.Identifier)
+		if err != nil || !spotifyUser(user) {
+			continue
+		}
+		candidates = append(candidates, member)
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoHandoverCandidate
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i].Permissions) != len(candidates[j].Permissions) {
+			return len(candidates[i].Permissions) > len(candidates[j].Permissions)
+		}
+		return candidates[i].Identifier < candidates[j].Identifier
+	})
+	return s.TransferHost(ctx, candidates[0].Identifier, true)
+}
+
+// handoverPlayback moves the playback of an active JamSession from the previous host to the new host. The current
+// song continues at its progress on the device of the new host and the playback of the previous host is stopped. A
+// song already handed over to the device of the previous host isn't moved, so the conductor starts the next song,
+// once the current one ended. If the new host has no playback device, the JamSession is deactivated.
+func (s *JamSession) handoverPlayback(ctx context.Context, previousHost string, newHost *users.User) {
+	settings, err := s.GetSettings()
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+	if !settings.Active {
+		return
+	}
+	var current *provider.Track
+	var progress int
+	if previous, err := s.hub.GetUserByIdentifier(ctx, previousHost); err == nil && previous.GetPlayerState().Playing {
+		if remaining, ok := previous.Remaining(s.clock.Now()); ok && remaining > 0 {
+			current = previous.GetPlayerState().Item
+			progress = current.Duration - int(remaining.Milliseconds())
+		}
+		if err := previous.SetState(ctx, false); err != nil {
+			log.Debug(err)
+		}
+	}
+	if newHost.GetPlayerState().Device.ID != "" {
+		if current == nil {
+			return
+		}
+		if err := newHost.PlayFrom(ctx, current, progress); err != nil {
+			log.WithField("Label", s.JamLabel).Debug("Could not continue ", current.ID, " on the device of the new host: ", err)
+		}
+		return
+	}
+	err = s.UpdateSettings(func(settings *Settings) error {
+		settings.Active = false
+		return nil
+	})
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+	s.SocketJamUpdate()
+}
+
+func spotifyUser(user *users.User) bool {
+	userInfo, err := user.GetInfo()
+	return err == nil && userInfo.UserType == users.UserTypeSpotify
+}
@@ -0,0 +1,55 @@
+package jamsession
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	"golang.org/x/oauth2"
+)
+
+func TestTransferHostPlayback(t *testing.T) {
+	j := newTestJam(t)
+	ctx := context.Background()
+	cohost, err := j.hub.NewUser(ctx, "cohost", "Co-Host", users.UserTypeSpotify, &oauth2.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.UpdateMembers(func(members *Members) error {
+		members.Add(cohost.Identifier, permissions.RoleCoHost.Permissions()...)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	song := j.track(t, "fake-track-00")
+	j.enqueue(t, "member", song)
+	j.update(t, func(settings *Settings) {
+		settings.Active = true
+	})
+	j.tickUntil(t, 15, func() bool {
+		return j.playing(t) != nil
+	})
+	j.tick(30)
+
+	if _, err := j.TransferHost(ctx, cohost.Identifier, false); err != nil {
+		t.Fatal(err)
+	}
+	if playing := j.playing(t); playing != nil {
+		t.Errorf("device of the previous host still plays %s", playing.ID)
+	}
+	state, err := cohost.Provider().(*provider.Fake).PlayerState(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Playing || state.Item == nil || state.Item.ID != song.ID {
+		t.Fatalf("device of the new host plays %v, want %s", state.Item, song.ID)
+	}
+	// The progress was last observed on the previous tick
+	if progress := time.Duration(state.Progress) * time.Millisecond; progress < 29*time.Second || progress > 30*time.Second {
+		t.Errorf("%s continues at %v, want about 30s", song.ID, progress)
+	}
+}
@@ -0,0 +1,132 @@
+package jamsession
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	log "github.com/sirupsen/logrus"
+)
+
+// inviteIDLength is the number of random bytes of an invite ID
+const inviteIDLength = 16
+
+var (
+	ErrInviteInvalid = errors.New("invite is invalid, expired or already used")
+)
+
+// Invite allows to join a JamSession without its password until ExpiresAt. A single use invite is removed,
+// once it was redeemed.
+type Invite struct {
+	ID        string
+	ExpiresAt time.Time
+	SingleUse bool
+}
+
+func (i *Invite) expired(now time.Time) bool {
+	return !now.Before(i.ExpiresAt)
+}
+
+// CreateInvite creates an invite for the JamSession, which expires after ttl
+func (s *JamSession) CreateInvite(ttl time.Duration, singleUse bool) (*Invite, error) {
+	id := make([]byte, inviteIDLength)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	invite := &Invite{
+		ID:        hex.EncodeToString(id),
+		ExpiresAt: s.clock.Now().Add(ttl),
+		SingleUse: singleUse,
+	}
+	err := s.UpdateSettings(func(settings *Settings) error {
+		settings.Invites = append(activeInvites(settings.Invites, s.clock.Now()), *invite)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Debug("Created invite expiring at ", invite.ExpiresAt)
+	return invite, nil
+}
+
+// CheckInvite returns ErrInviteInvalid, if the invite id isn't valid for the JamSession. It doesn't use up the invite.
+func (s *JamSession) CheckInvite(id string) error {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return err
+	}
+	for _, invite := range activeInvites(settings.Invites, s.clock.Now()) {
+		if invite.ID == id {
+			return nil
+		}
+	}
+	return ErrInviteInvalid
+}
+
+// JoinWithInvite adds the user identifier as guest to the JamSession with the invite id and uses up the invite, if
+// it is a single use invite. Invites and members are stored separately, so a used up invite is restored, if the
+// member can't be added.
+func (s *JamSession) JoinWithInvite(id string, identifier string) (*Members, error) {
+	var redeemed *Invite
+	err := s.UpdateSettings(func(settings *Settings) error {
+		redeemed = nil
+		if settings.Banned(identifier) {
+			return ErrUserBanned
+		}
+		invites := activeInvites(settings.Invites, s.clock.Now())
+		for i, invite := range invites {
+			if invite.ID != id {
+				continue
+			}
+			if invite.SingleUse {
+				redeemed = &invite
+				invites = append(invites[:i], invites[i+1:]...)
+			}
+			settings.Invites = invites
+			return nil
+		}
+		return ErrInviteInvalid
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var members *Members
+	err = s.UpdateMembers(func(current *Members) error {
+		current.Add(identifier, permissions.Guest)
+		members = current
+		return nil
+	})
+	if err != nil {
+		if redeemed != nil {
+			s.restoreInvite(*redeemed)
+		}
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Debug(identifier, " joined with an invite")
+	return members, nil
+}
+
+// restoreInvite adds the used up invite back to the JamSession
+func (s *JamSession) restoreInvite(invite Invite) {
+	err := s.UpdateSettings(func(settings *Settings) error {
+		settings.Invites = append(activeInvites(settings.Invites, s.clock.Now()), invite)
+		return nil
+	})
+	if err != nil {
+		log.WithField("Label", s.JamLabel).Warn("Couldn't restore invite: ", err)
+	}
+}
+
+// activeInvites returns the invites, which haven't expired at now
+func activeInvites(invites []Invite, now time.Time) []Invite {
+	active := make([]Invite, 0, len(invites))
+	for _, invite := range invites {
+		if !invite.expired(now) {
+			active = append(active, invite)
+		}
+	}
+	return active
+}
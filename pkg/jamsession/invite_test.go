@@ -0,0 +1,52 @@
+package jamsession
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
+)
+
+var errStore = errors.New("store unavailable")
+
+// failingMembers is a members store, whose updates fail
+type failingMembers struct {
+	store.Store[Members]
+}
+
+func (failingMembers) Update(key string, fn func(members *Members) error) error {
+	return errStore
+}
+
+func TestJoinWithInvite(t *testing.T) {
+	j := newTestJam(t)
+	invite, err := j.CreateInvite(time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The invite stays valid, if the member can't be added
+	members := j.stores.Members
+	j.stores.Members = failingMembers{members}
+	if _, err := j.JoinWithInvite(invite.ID, "guest"); err != errStore {
+		t.Fatalf("JoinWithInvite returned %v, want %v", err, errStore)
+	}
+	if err := j.CheckInvite(invite.ID); err != nil {
+		t.Fatalf("the invite wasn't restored: %v", err)
+	}
+
+	j.stores.Members = members
+	joined, err := j.JoinWithInvite(invite.ID, "guest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := joined.Get("guest"); err != nil {
+		t.Error("the guest wasn't added to the members")
+	}
+
+	// A single use invite is used up
+	if _, err := j.JoinWithInvite(invite.ID, "other"); err != ErrInviteInvalid {
+		t.Errorf("second JoinWithInvite returned %v, want %v", err, ErrInviteInvalid)
+	}
+}
@@ -0,0 +1,237 @@
+package jamsession
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/lease"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/presence"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	"golang.org/x/oauth2"
+)
+
+var epoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// steppedClock is a clock.Fake, which holds the conductor every time before it waits for its ticker or timer.
+// Tests move the clock with advance and inspect the JamSession in between without racing the conductor.
+type steppedClock struct {
+	*clock.Fake
+	paused  chan struct{}
+	resume  chan struct{}
+	stopped chan struct{}
+	mutex   sync.Mutex
+	waiting []<-chan time.Time
+}
+
+func newSteppedClock(now time.Time) *steppedClock {
+	return &steppedClock{
+		Fake:    clock.NewFake(now),
+		paused:  make(chan struct{}),
+		resume:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+func (c *steppedClock) NewTicker(d time.Duration) clock.Ticker {
+	ticker := c.Fake.NewTicker(d)
+	c.watch(ticker.C())
+	return &steppedTicker{Ticker: ticker, clock: c}
+}
+
+func (c *steppedClock) After(d time.Duration) <-chan time.Time {
+	ch := c.Fake.After(d)
+	c.watch(ch)
+	return ch
+}
+
+func (c *steppedClock) watch(ch <-chan time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.waiting = append(c.waiting, ch)
+}
+
+// due reports whether a tick or timer fired, which the conductor didn't receive yet
+func (c *steppedClock) due() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, ch := range c.waiting {
+		if len(ch) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// advance moves the clock forward by d and lets the conductor handle every tick and timer, which fired
+func (c *steppedClock) advance(d time.Duration) {
+	c.Fake.Advance(d)
+	for c.due() {
+		c.resume <- struct{}{}
+		<-c.paused
+	}
+}
+
+func (c *steppedClock) stop() {
+	close(c.stopped)
+}
+
+// steppedTicker pauses the conductor, whenever it is about to wait for the next tick
+type steppedTicker struct {
+	clock.Ticker
+	clock *steppedClock
+}
+
+func (t *steppedTicker) C() <-chan time.Time {
+	select {
+	case t.clock.paused <- struct{}{}:
+		select {
+		case <-t.clock.resume:
+		case <-t.clock.stopped:
+		}
+	case <-t.clock.stopped:
+	}
+	return t.Ticker.C()
+}
+
+// testJam is a JamSession hosted by a user of the fake provider, whose conductor is stepped by the clock
+type testJam struct {
+	*JamSession
+	clock  *steppedClock
+	host   *users.User
+	device *provider.Fake
+}
+
+func newTestJam(t *testing.T) *testJam {
+	t.Helper()
+	return newTestJamWithLeases(t, lease.NewMemory())
+}
+
+// newTestJamWithLeases creates a testJam, whose conductor acquires its lease from leases
+func newTestJamWithLeases(t *testing.T, leases lease.Leaser) *testJam {
+	t.Helper()
+	c := newSteppedClock(epoch)
+	h := hub.NewHub(provider.NewFakeFactory(c), c, hub.Stores{
+		Store:       store.NewMemoryStore[users.UserInformation](),
+		Identifiers: store.NewMemorySet(),
+	})
+	host, err := h.NewUser(context.Background(), "host", "Host", users.UserTypeSpotify, &oauth2.Token{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := CreateNew(host, Stores{
+		Members:  store.NewMemoryStore[Members](),
+		Queues:   store.NewMemoryStore[queue.Queue](),
+		Settings: store.NewMemoryStore[Settings](),
+	}, Cluster{
+		Broker:   notifications.NewMemoryBroker(),
+		Leases:   leases,
+		Presence: presence.NewMemory(c),
+	}, h, "TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the conductor to start
+	<-c.paused
+	t.Cleanup(func() {
+		c.stop()
+		if err := s.Deconstruct(); err != nil {
+			t.Error(err)
+		}
+	})
+	return &testJam{
+		JamSession: s,
+		clock:      c,
+		host:       host,
+		device:     host.Provider().(*provider.Fake),
+	}
+}
+
+// tick moves the clock forward by n seconds one tick at a time
+func (j *testJam) tick(n int) {
+	for i := 0; i < n; i++ {
+		j.clock.advance(time.Second)
+	}
+}
+
+// tickUntil ticks until cond is met and fails the test, if it isn't met within limit ticks
+func (j *testJam) tickUntil(t *testing.T, limit int, cond func() bool) {
+	t.Helper()
+	for i := 0; i < limit; i++ {
+		if cond() {
+			return
+		}
+		j.clock.advance(time.Second)
+	}
+	if !cond() {
+		t.Fatalf("condition not met after %d ticks", limit)
+	}
+}
+
+func (j *testJam) update(t *testing.T, fn func(settings *Settings)) {
+	t.Helper()
+	err := j.UpdateSettings(func(settings *Settings) error {
+		fn(settings)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (j *testJam) enqueue(t *testing.T, submitter string, tracks ...*provider.Track) {
+	t.Helper()
+	err := j.UpdateQueue(func(q *queue.Queue) error {
+		for _, track := range tracks {
+			if err := q.Vote(track.ID, submitter, track); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (j *testJam) queue(t *testing.T) *queue.Queue {
+	t.Helper()
+	q, err := j.GetQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func (j *testJam) track(t *testing.T, id string) *provider.Track {
+	t.Helper()
+	track, err := j.device.GetTrack(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return track
+}
+
+// playing returns the song the device plays, or nil
+func (j *testJam) playing(t *testing.T) *provider.Track {
+	t.Helper()
+	state, err := j.device.PlayerState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Playing {
+		return nil
+	}
+	return state.Item
+}
+
+func songDuration(track *provider.Track) time.Duration {
+	return time.Duration(track.Duration) * time.Millisecond
+}
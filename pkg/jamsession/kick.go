@@ -0,0 +1,142 @@
+package jamsession
+
+import (
+	"errors"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrKickHost      = errors.New("the host can't be kicked")
+	ErrKickSelf      = errors.New("members can't kick themselves")
+	ErrKickForbidden = errors.New("member has permissions the requester is missing")
+	ErrNotBanned     = errors.New("user is not banned")
+	ErrUserBanned    = errors.New("user is banned")
+)
+
+// Banned reports whether the user identifier is banned from the JamSession
+func (settings *Settings) Banned(identifier string) bool {
+	for _, banned := range settings.Bans {
+		if banned == identifier {
+			return true
+		}
+	}
+	return false
+}
+
+// Kick removes the user identifier from the JamSession on behalf of the member requester, together with all votes
+// of the user, and disconnects the clients of the user. If ban is set, the user can't join the JamSession again.
+// Users, who aren't members, e.g. because they wait in the lobby or already left, can only be banned.
+// Members can only kick members, whose permissions they were granted themselves.
+func (s *JamSession) Kick(requester string, identifier string, ban bool) (*Members, error) {
+	current, err := s.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	if err := current.checkKick(requester, identifier, ban); err != nil {
+		return nil, err
+	}
+
+	// The ban is recorded first, so the user can't join again, even if removing the member fails
+	pending := false
+	if ban {
+		err := s.UpdateSettings(func(settings *Settings) error {
+			if !settings.Banned(identifier) {
+				settings.Bans = append(settings.Bans, identifier)
+			}
+			pending = settings.removePending(identifier) == nil
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var members *Members
+	err = s.UpdateMembers(func(current *Members) error {
+		if err := current.checkKick(requester, identifier, ban); err != nil {
+			return err
+		}
+		current.Remove(identifier)
+		members = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Info("Kicked ", identifier, ", banned: ", ban)
+
+	err = s.UpdateQueue(func(q *queue.Queue) error {
+		q.RemoveVotes(identifier)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.SocketQueueUpdate()
+	if pending {
+		s.notifyDecision(identifier, false)
+	}
+	s.NotifyClients(&notifications.Message{
+		Event: notifications.Kick,
+		Message: types.SocketKickMessage{
+			Identifier: identifier,
+			Banned:     ban,
+		},
+		Disconnect: identifier,
+	})
+	return members, nil
+}
+
+// checkKick returns why the member requester can't kick the user identifier
+func (m Members) checkKick(requester string, identifier string, ban bool) error {
+	requestingMember, err := m.Get(requester)
+	if err != nil {
+		return err
+	}
+	if requester == identifier {
+		return ErrKickSelf
+	}
+	member, err := m.Get(identifier)
+	if err == ErrNotMember && ban {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if member.HasPermissions(permissions.Host) {
+		return ErrKickHost
+	}
+	if !requestingMember.Can(member.GetPermissions()...) {
+		return ErrKickForbidden
+	}
+	return nil
+}
+
+// Unban allows the user identifier to join the JamSession again
+func (s *JamSession) Unban(identifier string) (*Settings, error) {
+	var settings *Settings
+	err := s.UpdateSettings(func(current *Settings) error {
+		bans := make([]string, 0, len(current.Bans))
+		for _, banned := range current.Bans {
+			if banned != identifier {
+				bans = append(bans, banned)
+			}
+		}
+		if len(bans) == len(current.Bans) {
+			return ErrNotBanned
+		}
+		current.Bans = bans
+		settings = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Info("Unbanned ", identifier)
+	return settings, nil
+}
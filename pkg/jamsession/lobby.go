@@ -0,0 +1,124 @@
+package jamsession
+
+import (
+	"errors"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNotPending = errors.New("user is not waiting for approval")
+)
+
+// Waiting reports whether the user identifier waits for the approval to join the JamSession
+func (settings *Settings) Waiting(identifier string) bool {
+	for _, pending := range settings.Pending {
+		if pending == identifier {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestApproval puts the user identifier into the lobby of the JamSession, until a member approves or
+// rejects the request
+func (s *JamSession) RequestApproval(identifier string) (*Settings, error) {
+	var settings *Settings
+	err := s.UpdateSettings(func(current *Settings) error {
+		if !current.Waiting(identifier) {
+			current.Pending = append(current.Pending, identifier)
+		}
+		settings = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Debug(identifier, " waits for approval")
+	return settings, nil
+}
+
+// Approve adds the waiting user identifier as guest to the JamSession and notifies the user. Banned users can't be
+// approved.
+func (s *JamSession) Approve(identifier string) (*Settings, error) {
+	var settings *Settings
+	err := s.UpdateSettings(func(current *Settings) error {
+		if current.Banned(identifier) {
+			return ErrUserBanned
+		}
+		if err := current.removePending(identifier); err != nil {
+			return err
+		}
+		settings = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = s.UpdateMembers(func(current *Members) error {
+		current.Add(identifier, permissions.Guest)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Info("Approved ", identifier)
+	s.notifyDecision(identifier, true)
+	return settings, nil
+}
+
+// Reject removes the waiting user identifier from the lobby and notifies the user
+func (s *JamSession) Reject(identifier string) (*Settings, error) {
+	settings, err := s.removePending(identifier)
+	if err != nil {
+		return nil, err
+	}
+	log.WithField("Label", s.JamLabel).Info("Rejected ", identifier)
+	s.notifyDecision(identifier, false)
+	return settings, nil
+}
+
+// Withdraw removes the user identifier from the lobby, when the user doesn't want to wait any longer
+func (s *JamSession) Withdraw(identifier string) (*Settings, error) {
+	return s.removePending(identifier)
+}
+
+func (s *JamSession) removePending(identifier string) (*Settings, error) {
+	var settings *Settings
+	err := s.UpdateSettings(func(current *Settings) error {
+		if err := current.removePending(identifier); err != nil {
+			return err
+		}
+		settings = current
+		return nil
+	})
+	return settings, err
+}
+
+// removePending removes the user identifier from the lobby
+func (settings *Settings) removePending(identifier string) error {
+	pending := make([]string, 0, len(settings.Pending))
+	for _, waiting := range settings.Pending {
+		if waiting != identifier {
+			pending = append(pending, waiting)
+		}
+	}
+	if len(pending) == len(settings.Pending) {
+		return ErrNotPending
+	}
+	settings.Pending = pending
+	return nil
+}
+
+func (s *JamSession) notifyDecision(identifier string, approved bool) {
+	s.NotifyClients(&notifications.Message{
+		Event: notifications.Approval,
+		Message: types.SocketApprovalMessage{
+			Approved: approved,
+		},
+		Recipient: identifier,
+	})
+}
@@ -1,8 +1,18 @@
 package jamsession
 
 import (
+	"context"
 	"errors"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrHostMissing = errors.New("no host found")
+	ErrNotMember   = errors.New("not a member")
 )
 
 type Member struct {
@@ -57,6 +67,11 @@ func (m *Member) HasPermissions(p ...permissions.Permission) bool {
 	return true
 }
 
+// Can reports whether the member was granted all permissions p, either directly or implied by its other permissions
+func (m *Member) Can(p ...permissions.Permission) bool {
+	return m.GetPermissions().Grants(p...)
+}
+
 type Members map[string]*Member
 
 func (m Members) Host() (*Member, error) {
@@ -65,14 +80,14 @@ func (m Members) Host() (*Member, error) {
 			return member, nil
 		}
 	}
-	return nil, errors.New("no host found")
+	return nil, ErrHostMissing
 }
 
 func (m Members) Get(identifier string) (*Member, error) {
 	if member, ok := m[identifier]; ok {
 		return member, nil
 	}
-	return nil, errors.New("not a member")
+	return nil, ErrNotMember
 }
 
 func (m Members) Add(identifier string, p ...permissions.Permission) bool {
@@ -90,3 +105,28 @@ func (m Members) Remove(identifier string) bool {
 	}
 	return false
 }
+
+// Response returns the API representation of the members with the names of their users. Members, whose user
+// doesn't exist anymore, are left out.
+func (m Members) Response(ctx context.Context, users *hub.Hub) types.GetJamMembersResponse {
+	memberResponse := make([]types.JamMember, 0)
+	for _, member := range m {
+		user, err := users.GetUserByIdentifier(ctx, member.GetIdentifier())
+		if err != nil {
+			log.Warn("User for identifier not found", member.GetIdentifier())
+			continue
+		}
+		userInfo, err := user.GetInfo()
+		if err != nil {
+			log.Warn("UserInfo for identifier not found", member.GetIdentifier())
+			continue
+		}
+		memberResponse = append(memberResponse, types.JamMember{
+			DisplayName: userInfo.UserName,
+			Identifier:  user.Identifier,
+			Role:        permissions.RoleOf(member.GetPermissions()),
+			Permissions: member.GetPermissions(),
+		})
+	}
+	return types.GetJamMembersResponse{Members: memberResponse}
+}
@@ -0,0 +1,68 @@
+package jamsession
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxPasswordLength is the maximum length in bytes bcrypt accepts
+const maxPasswordLength = 72
+
+var (
+	ErrPasswordTooLong = errors.New("password is too long")
+)
+
+// HashPassword returns the hash of password, which is stored in Settings.Password. An empty password
+// stays empty, as it disables the password of the JamSession.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	if len(password) > maxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches the password of the JamSession
+func (settings *Settings) CheckPassword(password string) bool {
+	if settings.Password == "" {
+		return password == ""
+	}
+	if !settings.passwordHashed() {
+		return subtle.ConstantTimeCompare([]byte(settings.Password), []byte(password)) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(settings.Password), []byte(password)) == nil
+}
+
+// passwordHashed reports whether the password is a hash. Passwords set by previous versions are stored in plaintext
+func (settings *Settings) passwordHashed() bool {
+	_, err := bcrypt.Cost([]byte(settings.Password))
+	return err == nil
+}
+
+// hashPlaintextPassword replaces a password stored in plaintext by its hash
+func (s *JamSession) hashPlaintextPassword() {
+	err := s.UpdateSettings(func(settings *Settings) error {
+		if settings.Password == "" || settings.passwordHashed() {
+			return nil
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(settings.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		settings.Password = string(hash)
+		log.WithField("Label", s.JamLabel).Info("Hashed plaintext password")
+		return nil
+	})
+	if err != nil {
+		log.WithField("Label", s.JamLabel).Warn("Could not hash plaintext password: ", err)
+	}
+}
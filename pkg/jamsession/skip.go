@@ -0,0 +1,172 @@
+package jamsession
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultDownvoteThreshold = 3
+	DefaultSkipPercentage    = 50
+)
+
+var (
+	ErrNothingPlaying = errors.New("nothing is playing")
+)
+
+// SkipVotesRequired returns the number of votes needed to skip a song, when members members are connected
+func (settings *Settings) SkipVotesRequired(members int) int {
+	percentage := settings.SkipPercentage
+	if percentage <= 0 {
+		percentage = DefaultSkipPercentage
+	}
+	// Round up, so the percentage is always reached
+	required := (members*percentage + 99) / 100
+	if required < 1 {
+		return 1
+	}
+	return required
+}
+
+func (s *JamSession) Downvote(songID string, voteID string) error {
+	err := s.UpdateQueue(func(q *queue.Queue) error {
+		return q.Downvote(songID, voteID)
+	})
+	if err != nil {
+		return err
+	}
+	s.SocketQueueUpdate()
+	return nil
+}
+
+// VoteSkip flips the vote of voteID to skip the song the host is currently playing.
+// The conductor skips the song, once enough members voted.
+func (s *JamSession) VoteSkip(ctx context.Context, voteID string) (*types.SkipResponse, error) {
+	members, err := s.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	hostMember, err := members.Host()
+	if err != nil {
+		return nil, err
+	}
+	host, err := s.hub.GetUserByIdentifier(ctx, hostMember.Identifier)
+	if err != nil {
+		return nil, err
+	}
+	item := host.GetPlayerState().Item
+	if item == nil {
+		return nil, ErrNothingPlaying
+	}
+
+	var currentQueue *queue.Queue
+	err = s.UpdateQueue(func(q *queue.Queue) error {
+		q.VoteSkip(item.ID, voteID)
+		currentQueue = q
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	progress, err := s.skipProgress(currentQueue, item.ID, s.connectedMembers(members))
+	if err != nil {
+		return nil, err
+	}
+	s.NotifyClients(&notifications.Message{
+		Event:   notifications.Skip,
+		Message: types.SocketSkipMessage(*progress),
+	})
+	return &types.SkipResponse{
+		SkipProgress: *progress,
+		Voted:        currentQueue.HasSkipVote(item.ID, voteID),
+	}, nil
+}
+
+// connectedMembers counts the members connected to the JamSession through any backend instance. Members, who closed
+// the app without leaving, would keep the votes needed to skip a song out of reach otherwise.
+func (s *JamSession) connectedMembers(members *Members) int {
+	connected, err := s.cluster.Presence.Connected(s.JamLabel)
+	if err != nil {
+		log.WithField("Label", s.JamLabel).Warn("Could not get the connected members: ", err)
+		// Count the members connected to this instance at least
+		connected = make(map[string]bool)
+		for _, identifier := range s.room.Connected() {
+			connected[identifier] = true
+		}
+	}
+	count := 0
+	for identifier := range *members {
+		if connected[identifier] {
+			count++
+		}
+	}
+	return count
+}
+
+// skipDue reports whether enough of the connected members voted to skip the song songID
+func (s *JamSession) skipDue(q *queue.Queue, settings *Settings, songID string, members int) bool {
+	votes := q.SkipVotes(songID)
+	return votes > 0 && votes >= settings.SkipVotesRequired(members)
+}
+
+// skip starts the next song of the queue or, if the queue is empty, of the fallback source. Without a fallback song
+// the playback of the host is paused.
+func (s *JamSession) skip(ctx context.Context, q *queue.Queue, settings *Settings, host *users.User) error {
+	err := s.playNext(ctx, q, settings, host)
+	if err != queue.ErrQueueEmpty {
+		return err
+	}
+	if err := host.SetState(ctx, false); err != nil {
+		return err
+	}
+	err = s.UpdateQueue(func(q *queue.Queue) error {
+		q.Skip = queue.SkipVote{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.SocketSkipUpdate("")
+	return nil
+}
+
+func (s *JamSession) skipProgress(q *queue.Queue, songID string, members int) (*types.SkipProgress, error) {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &types.SkipProgress{
+		TrackID:  songID,
+		Votes:    q.SkipVotes(songID),
+		Required: settings.SkipVotesRequired(members),
+	}, nil
+}
+
+// SocketSkipUpdate sends the skip progress of the song songID to all clients
+func (s *JamSession) SocketSkipUpdate(songID string) {
+	members, err := s.GetMembers()
+	if err != nil {
+		log.Warn("could not get members", err)
+		return
+	}
+	q, err := s.GetQueue()
+	if err != nil {
+		log.Warn("could not get queue", err)
+		return
+	}
+	progress, err := s.skipProgress(q, songID, s.connectedMembers(members))
+	if err != nil {
+		log.Warn("could not get settings", err)
+		return
+	}
+	s.NotifyClients(&notifications.Message{
+		Event:   notifications.Skip,
+		Message: types.SocketSkipMessage(*progress),
+	})
+}
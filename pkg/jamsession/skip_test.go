@@ -0,0 +1,35 @@
+package jamsession
+
+import (
+	"testing"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+)
+
+func TestConnectedMembers(t *testing.T) {
+	j := newTestJam(t)
+	err := j.UpdateMembers(func(members *Members) error {
+		members.Add("local", permissions.Guest)
+		members.Add("remote", permissions.Guest)
+		members.Add("offline", permissions.Guest)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One member is connected to this instance and another one to another instance
+	if err := j.cluster.Presence.Announce(j.JamLabel, []string{"local"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.cluster.Presence.Announce(j.JamLabel, []string{"remote", "left"}); err != nil {
+		t.Fatal(err)
+	}
+	members, err := j.GetMembers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if connected := j.connectedMembers(members); connected != 2 {
+		t.Errorf("%d members connected, want the local and the remote member", connected)
+	}
+}
@@ -2,12 +2,19 @@ package jamsession
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/lease"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/presence"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
 
@@ -16,7 +23,6 @@ import (
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
 	log "github.com/sirupsen/logrus"
-	"github.com/zmb3/spotify/v2"
 )
 
 const (
@@ -25,6 +31,9 @@ const (
 	UpdateIntervalSync     int = 1
 )
 
+// activityInterval is the minimum interval in which activity is shared with the other backend instances
+const activityInterval = time.Minute
+
 var (
 	ErrCollectionTypeInvalid     = errors.New("invalid collection type")
 	ErrCouldNotGetAlbum          = errors.New("could not get album")
@@ -38,24 +47,62 @@ type Stores struct {
 	Settings store.Store[Settings]
 }
 
+// Cluster coordinates a JamSession between backend instances. The conductor runs on the instance
+// holding the lease of the JamLabel, while notifications are published to all instances. Every instance announces
+// the members connected to it to Presence.
+type Cluster struct {
+	Broker   notifications.Broker
+	Leases   lease.Leaser
+	Presence presence.Tracker
+}
+
+// Settings of a JamSession. Password is the bcrypt hash of the password, or empty if the JamSession has no
+// password. Bans contains the identifiers of the users, which aren't allowed to join the JamSession.
+// Invites contains the invites, which can be redeemed to join the JamSession. If RequireApproval is set,
+// joining users wait in Pending, until a member approves or rejects them. Quota limits the songs members can add
+// and Filter the tracks, which can be added. Replay keeps songs from being queued again right after they were played.
+// Fallback is the source of the songs played, when the queue runs dry. The next song is handed over to the device of
+// the host LeadTime before the end of the current song.
 type Settings struct {
-	Name     string
-	Active   bool
-	Password string
+	Name              string
+	Active            bool
+	Password          string
+	Ordering          queue.Ordering
+	DownvoteThreshold int
+	SkipPercentage    int
+	AutoHandover      bool
+	RequireApproval   bool
+	Quota             queue.Quota
+	Filter            Filter
+	Replay            queue.Replay
+	Fallback          Fallback
+	LeadTime          time.Duration
+	Bans              []string
+	Invites           []Invite
+	Pending           []string
 }
 
 type JamLabel string
 
 type JamSession struct {
-	JamLabel  string
-	stores    Stores
-	hub       *hub.Hub
-	Timestamp time.Time
-	room      *notifications.Room
-	quit      chan bool
+	JamLabel          string
+	stores            Stores
+	cluster           Cluster
+	hub               *hub.Hub
+	clock             clock.Clock
+	timestamp         time.Time
+	activityPublished time.Time
+	timestampMutex    sync.RWMutex
+	conducting        atomic.Bool
+	fallbackLoaded    atomic.Int64
+	handoff           *handoff
+	leaseRenewed      time.Time
+	presenceAnnounced time.Time
+	room              *notifications.Room
+	quit              chan bool
 }
 
-func CreateNew(host *users.User, stores Stores, hub *hub.Hub, label string) (*JamSession, error) {
+func CreateNew(host *users.User, stores Stores, cluster Cluster, hub *hub.Hub, label string) (*JamSession, error) {
 	members := &Members{
 		host.Identifier: NewMember(host.Identifier, permissions.Guest, permissions.Host),
 	}
@@ -65,21 +112,28 @@ func CreateNew(host *users.User, stores Stores, hub *hub.Hub, label string) (*Ja
 	}
 
 	settings := &Settings{
-		Name:     fmt.Sprintf("%s's JamSession", userInfo.UserName),
-		Active:   false,
-		Password: "",
+		Name:              fmt.Sprintf("%s's JamSession", userInfo.UserName),
+		Active:            false,
+		Password:          "",
+		Ordering:          queue.OrderingVotes,
+		DownvoteThreshold: DefaultDownvoteThreshold,
+		SkipPercentage:    DefaultSkipPercentage,
+		AutoHandover:      true,
+		LeadTime:          DefaultLeadTime,
 	}
 
 	currentQueue := queue.New()
 
 	s := &JamSession{
 		JamLabel:  label,
-		Timestamp: time.Now(),
+		timestamp: hub.Clock.Now(),
 		hub:       hub,
-		room:      notifications.NewRoom(),
+		clock:     hub.Clock,
 		quit:      make(chan bool),
 		stores:    stores,
+		cluster:   cluster,
 	}
+	s.room = notifications.NewRoom()
 
 	if err = s.SetMembers(members); err != nil {
 		return nil, err
@@ -91,27 +145,96 @@ func CreateNew(host *users.User, stores Stores, hub *hub.Hub, label string) (*Ja
 		return nil, err
 	}
 
+	if err = cluster.Broker.Subscribe(label, s.deliver); err != nil {
+		return nil, err
+	}
 	go s.room.OpenDoors()
 	go s.Conductor()
 	log.WithField("Label", label).Info("Created new JamSession")
 	return s, nil
 }
 
-func Load(stores Stores, hub *hub.Hub, label string) (*JamSession, error) {
+func Load(stores Stores, cluster Cluster, hub *hub.Hub, label string) (*JamSession, error) {
 	s := &JamSession{
 		JamLabel:  label,
-		Timestamp: time.Now(),
+		timestamp: hub.Clock.Now(),
 		hub:       hub,
-		room:      notifications.NewRoom(),
+		clock:     hub.Clock,
 		quit:      make(chan bool),
 		stores:    stores,
+		cluster:   cluster,
+	}
+	s.room = notifications.NewRoom()
+	if err := cluster.Broker.Subscribe(label, s.deliver); err != nil {
+		return nil, err
 	}
+	s.hashPlaintextPassword()
 	go s.Conductor()
 	go s.room.OpenDoors()
 	log.WithField("Label", label).Info("Loaded JamSession from store")
 	return s, nil
 }
 
+// Timestamp returns the time of the last activity in the JamSession
+func (s *JamSession) Timestamp() time.Time {
+	s.timestampMutex.RLock()
+	defer s.timestampMutex.RUnlock()
+	return s.timestamp
+}
+
+// Touch marks the JamSession as active. The activity is shared with the other backend instances,
+// so the JamSession isn't closed as inactive by the instance conducting it.
+func (s *JamSession) Touch() {
+	now := s.clock.Now()
+	s.timestampMutex.Lock()
+	s.timestamp = now
+	publish := now.Sub(s.activityPublished) > activityInterval
+	if publish {
+		s.activityPublished = now
+	}
+	s.timestampMutex.Unlock()
+
+	if publish {
+		s.publish(&notifications.Message{Event: notifications.Activity})
+	}
+}
+
+// Conducting reports whether this backend instance holds the lease of the JamSession and runs its conductor
+func (s *JamSession) Conducting() bool {
+	return s.conducting.Load()
+}
+
+// renewLease acquires or renews the lease of the JamSession, if it is due, and reports whether it is held
+func (s *JamSession) renewLease() bool {
+	if s.clock.Now().Sub(s.leaseRenewed) < lease.RenewInterval {
+		return s.Conducting()
+	}
+	held, err := s.cluster.Leases.Acquire(s.JamLabel)
+	if err != nil {
+		// Without a confirmed lease another instance might conduct, so stay passive
+		log.WithField("Label", s.JamLabel).Warn("Could not acquire lease: ", err)
+		held = false
+	}
+	if held != s.Conducting() {
+		log.WithField("Label", s.JamLabel).Info("Conducting JamSession: ", held)
+	}
+	s.conducting.Store(held)
+	s.leaseRenewed = s.clock.Now()
+	return held
+}
+
+// announcePresence announces the members connected to this instance again, if it is due
+func (s *JamSession) announcePresence() {
+	if s.clock.Now().Sub(s.presenceAnnounced) < presence.RenewInterval {
+		return
+	}
+	if err := s.cluster.Presence.Announce(s.JamLabel, s.room.Connected()); err != nil {
+		log.WithField("Label", s.JamLabel).Warn("Could not announce the connected members: ", err)
+		return
+	}
+	s.presenceAnnounced = s.clock.Now()
+}
+
 func (s *JamSession) GetQueue() (*queue.Queue, error) {
 	return s.stores.Queues.Get(s.JamLabel)
 }
@@ -120,6 +243,23 @@ func (s *JamSession) SetQueue(queue *queue.Queue) error {
 	return s.stores.Queues.Save(queue, s.JamLabel)
 }
 
+// UpdateQueue atomically applies fn to the queue of the JamSession, removes downvoted songs and sorts it with the
+// configured ordering. fn may be called more than once
+func (s *JamSession) UpdateQueue(fn func(q *queue.Queue) error) error {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return err
+	}
+	return s.stores.Queues.Update(s.JamLabel, func(q *queue.Queue) error {
+		if err := fn(q); err != nil {
+			return err
+		}
+		q.RemoveDownvoted(settings.DownvoteThreshold)
+		q.Sort(settings.Ordering)
+		return nil
+	})
+}
+
 func (s *JamSession) GetMembers() (*Members, error) {
 	return s.stores.Members.Get(s.JamLabel)
 }
@@ -128,6 +268,11 @@ func (s *JamSession) SetMembers(members *Members) error {
 	return s.stores.Members.Save(members, s.JamLabel)
 }
 
+// UpdateMembers atomically applies fn to the members of the JamSession. fn may be called more than once
+func (s *JamSession) UpdateMembers(fn func(members *Members) error) error {
+	return s.stores.Members.Update(s.JamLabel, fn)
+}
+
 func (s *JamSession) GetSettings() (*Settings, error) {
 	return s.stores.Settings.Get(s.JamLabel)
 }
@@ -136,31 +281,61 @@ func (s *JamSession) SetSettings(settings *Settings) error {
 	return s.stores.Settings.Save(settings, s.JamLabel)
 }
 
+// UpdateSettings atomically applies fn to the settings of the JamSession. fn may be called more than once
+func (s *JamSession) UpdateSettings(fn func(settings *Settings) error) error {
+	return s.stores.Settings.Update(s.JamLabel, fn)
+}
+
 func (s *JamSession) Conductor() {
-	ticker := time.NewTicker(time.Second)
+	ticker := s.clock.NewTicker(time.Second)
 	intervalCount := 0
 	updateInterval := UpdateIntervalInactive
 	defer ticker.Stop()
+	// handoffTimer fires, when the next song needs to be handed over between two ticks
+	var handoffTimer <-chan time.Time
 	for {
 		select {
 
 		// Fire conductor if he isn't needed anymore
 		case <-s.quit:
+			if s.Conducting() {
+				if err := s.cluster.Leases.Release(s.JamLabel); err != nil {
+					log.Warn(err)
+				}
+			}
 			return
 
+		case <-handoffTimer:
+			handoffTimer = nil
+			if err := s.handOverScheduled(context.Background()); err != nil {
+				log.Error(err)
+			}
+
 		// Update player state and send it to all connected clients
-		case <-ticker.C:
+		case <-ticker.C():
+			conducting := s.renewLease()
+			s.announcePresence()
+			// Only the instance holding the lease polls the players. The other instances learn the player state of
+			// the host from the published playback.
+			if !conducting {
+				// Poll the players right away, once this instance takes over
+				intervalCount = updateInterval
+				continue
+			}
 			members, err := s.GetMembers()
 			if err != nil {
 				log.Warn(err)
+				continue
 			}
 			settings, err := s.GetSettings()
 			if err != nil {
 				log.Warn(err)
+				continue
 			}
 			currentQueue, err := s.GetQueue()
 			if err != nil {
 				log.Warn(err)
+				continue
 			}
 			// Get the host user
 			hostMember, err := members.Host()
@@ -192,59 +367,104 @@ func (s *JamSession) Conductor() {
 				// If the intervalCount is reached, update the PlayerState for each spotify user
 				if intervalCount >= updateInterval {
 
-					playerState, err := user.Client().PlayerState(context.Background())
+					playerState, err := user.Provider().PlayerState(context.Background())
 					if err != nil {
 						continue
 					}
 
 					user.SetPlayerState(playerState)
-
-					if !user.Synchronized {
-						user.SyncCount++
-						if user.SyncCount >= 1 {
-							user.Synchronized = true
-							user.SyncCount = 0
-						}
-					}
+					user.Synchronize()
 				}
 
 				// Check if the user started a song
-				if user.Synchronized && user.GetPlayerState().Item != nil && user.CurrentSong != nil && user.GetPlayerState().Item.ID != user.CurrentSong.ID {
-					user.Active = false
-					user.CurrentSong = nil
+				if user.SongChanged() {
 					if user.Identifier == host.Identifier {
-						settings.Active = false
-						if err := s.SetSettings(settings); err != nil {
+						err := s.UpdateSettings(func(settings *Settings) error {
+							settings.Active = false
+							return nil
+						})
+						if err != nil {
 							log.Warn(err)
 							continue
 						}
+						settings.Active = false
 						s.SocketJamUpdate()
 					}
 				}
 			}
 
+			// Record the song the device of the host reached after the handoff
+			if track := host.HandedOver(); track != nil {
+				if err := s.recordHandoff(host, track); err != nil {
+					log.Error(err)
+				}
+				s.Touch()
+				if currentQueue, err = s.GetQueue(); err != nil {
+					log.Warn(err)
+					continue
+				}
+			}
+
 			s.SocketPlaybackUpdate(host)
 
-			// Check if no start or end of song is near for the host
-			if settings.Active && host.Synchronized {
+			// Skip the current song of the host, if enough members voted for it
+			skipped := false
+			if item := host.GetPlayerState().Item; settings.Active && item != nil &&
+				s.skipDue(currentQueue, settings, item.ID, s.connectedMembers(members)) {
+				if err := s.skip(context.Background(), currentQueue, settings, host); err != nil {
+					log.Error(err)
+				}
+				s.Touch()
+				skipped = true
+			}
+
+			// Songs queued while a song of the fallback source is playing replace it right away
+			if item := host.GetPlayerState().Item; settings.Active && !skipped && item != nil &&
+				len(currentQueue.Songs) > 0 && currentQueue.PlayingFallback(item.ID) {
+				if err := s.Play(context.Background(), currentQueue.Songs[0].Track, true); err != nil {
+					log.Error(err)
+				}
+				s.Touch()
+				skipped = true
+			}
+
+			// Start the next song, if nothing is playing for the host
+			if settings.Active && !skipped && host.Synchronized() &&
+				!host.GetPlayerState().Playing && host.GetPlayerState().Progress == 0 {
 				so, err := currentQueue.GetNext()
 				switch err {
 				case nil:
-					if (!host.GetPlayerState().Playing && host.GetPlayerState().Progress == 0) ||
-						(host.GetPlayerState().Item != nil && host.GetPlayerState().Progress > host.GetPlayerState().Item.Duration-1000) {
-						if err := s.Play(context.Background(), so.Track, true); err != nil {
-							log.Error(err)
-							continue
-						}
-						s.Timestamp = time.Now()
+					if err := s.Play(context.Background(), so.Track, true); err != nil {
+						log.Error(err)
+						continue
 					}
+					s.Touch()
 				case queue.ErrQueueEmpty:
-
+					// Keep the music going with the fallback source
+					if err := s.playFallback(context.Background(), settings, host); err != nil {
+						log.Error(err)
+						continue
+					}
 				default:
 					log.Error(err)
 				}
 			}
 
+			// Hand the next song over to the device of the host shortly before the predicted end of the current song.
+			// The end is predicted from the last observed progress, so it doesn't depend on the update interval.
+			remaining, playing := host.Remaining(s.clock.Now())
+			leadTime := settings.EffectiveLeadTime()
+			if settings.Active && !skipped && playing && host.Synchronized() {
+				if remaining <= leadTime {
+					if err := s.handOver(context.Background(), settings, host); err != nil {
+						log.Error(err)
+					}
+				} else if remaining-leadTime < time.Second && handoffTimer == nil {
+					// The next tick would be too late
+					handoffTimer = s.clock.After(remaining - leadTime)
+				}
+			}
+
 			// Reset the interval count
 			if intervalCount >= updateInterval {
 				intervalCount = 0
@@ -253,9 +473,9 @@ func (s *JamSession) Conductor() {
 			}
 
 			// Set the current update Interval
-			if host.GetPlayerState().Playing && host.GetPlayerState().Item != nil {
-				if host.GetPlayerState().Progress > host.GetPlayerState().Item.Duration-6000 {
-					// First and last 5 seconds of the current song. Sync fast to correctly display switching the song
+			if playing {
+				if remaining < leadTime+syncLeadTime {
+					// Sync fast around the handoff to correctly display switching the song
 					updateInterval = UpdateIntervalSync
 				} else {
 					// We are in the middle of the song. Decrease sync rate
@@ -265,7 +485,7 @@ func (s *JamSession) Conductor() {
 				// JamSession is inactive and no playback needs to be updated
 				updateInterval = UpdateIntervalInactive
 			}
-			if !host.Synchronized {
+			if !host.Synchronized() {
 				// Conductor is not synchronized.
 				updateInterval = UpdateIntervalSync
 			}
@@ -274,9 +494,14 @@ func (s *JamSession) Conductor() {
 		}
 	}
 }
-func (s *JamSession) Play(ctx context.Context, track *spotify.FullTrack, remove bool) error {
+func (s *JamSession) Play(ctx context.Context, track *provider.Track, remove bool) error {
+	return s.play(ctx, track, remove, false)
+}
+
+// play plays track on the device of the host and records it in the history. fallback marks songs of the
+// fallback source.
+func (s *JamSession) play(ctx context.Context, track *provider.Track, remove bool, fallback bool) error {
 	members, err := s.GetMembers()
-	currentQueue, err := s.GetQueue()
 	if err != nil {
 		return err
 	}
@@ -292,33 +517,113 @@ func (s *JamSession) Play(ctx context.Context, track *spotify.FullTrack, remove
 	if err != nil {
 		return err
 	}
-	if remove {
-		currentQueue.Delete(track.ID.String())
-	}
-	err = s.SetQueue(currentQueue)
+	startedAt := s.clock.Now()
+	skipVotes := false
+	err = s.UpdateQueue(func(q *queue.Queue) error {
+		skipVotes = len(q.Skip.Voters) > 0
+		q.Advance(track, startedAt).Fallback = fallback
+		if remove {
+			q.Delete(track.ID)
+		}
+		if fallback {
+			q.RemoveFallback(track.ID)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 	s.SocketQueueUpdate()
+	if skipVotes {
+		s.SocketSkipUpdate(track.ID)
+	}
 
 	return nil
 }
 
 func (s *JamSession) Deconstruct() error {
+	if err := s.cluster.Broker.Unsubscribe(s.JamLabel); err != nil {
+		return err
+	}
 	s.room.CloseDoors()
 	s.quit <- true
 	return nil
 }
 
+// Shutdown closes the JamSession on this backend instance only. The local clients are notified with reason
+// and the lease is released, so another instance can take over the JamSession.
+func (s *JamSession) Shutdown(ctx context.Context, reason notifications.WebsocketCloseType) error {
+	s.room.Send(&notifications.Message{
+		Event:   notifications.Close,
+		Message: reason,
+	})
+	if err := s.Deconstruct(); err != nil {
+		return err
+	}
+	return s.room.Wait(ctx)
+}
+
+// NotifyClients sends msg to the websocket clients connected to any backend instance
 func (s *JamSession) NotifyClients(msg *notifications.Message) {
-	if len(s.room.Clients) > 0 {
-		s.room.Broadcast <- msg
+	s.publish(msg)
+}
+
+func (s *JamSession) publish(msg *notifications.Message) {
+	if err := s.cluster.Broker.Publish(s.JamLabel, msg); err != nil {
+		log.WithField("Label", s.JamLabel).Warn("Could not publish message: ", err)
+	}
+}
+
+// deliver handles the messages published for the JamSession by any backend instance
+func (s *JamSession) deliver(msg *notifications.Message) {
+	if msg.Event == notifications.Activity {
+		s.timestampMutex.Lock()
+		s.timestamp = s.clock.Now()
+		s.timestampMutex.Unlock()
+		return
+	}
+	if msg.Event == notifications.Playback && !s.Conducting() {
+		s.observePlayback(msg)
 	}
+	s.room.Send(msg)
+}
+
+// observePlayback takes over the player state of the host published by the conducting instance, so requests to this
+// instance see the current playback without polling the player of the host
+func (s *JamSession) observePlayback(msg *notifications.Message) {
+	playback, ok := msg.Message.(types.SocketPlaybackMessage)
+	if !ok {
+		// Messages of other instances are decoded without their type
+		data, err := json.Marshal(msg.Message)
+		if err == nil {
+			err = json.Unmarshal(data, &playback)
+		}
+		if err != nil {
+			log.WithField("Label", s.JamLabel).Warn("Could not decode playback: ", err)
+			return
+		}
+	}
+	if playback.Playback == nil {
+		return
+	}
+	members, err := s.GetMembers()
+	if err != nil {
+		log.WithField("Label", s.JamLabel).Warn(err)
+		return
+	}
+	hostMember, err := members.Host()
+	if err != nil {
+		return
+	}
+	host, err := s.hub.GetUserByIdentifier(context.Background(), hostMember.Identifier)
+	if err != nil {
+		return
+	}
+	host.SetPlayerState(playback.Playback)
 }
 
 func (s *JamSession) AddCollection(ctx context.Context, collectionType string, collectionID string) error {
 	members, err := s.GetMembers()
-	currentQueue, err := s.GetQueue()
 	if err != nil {
 		return err
 	}
@@ -330,54 +635,51 @@ func (s *JamSession) AddCollection(ctx context.Context, collectionType string, c
 	if err != nil {
 		return err
 	}
+	var tracks []*provider.Track
 	switch collectionType {
 	case "playlist":
-		playlist, err := host.Client().GetPlaylistItems(ctx, spotify.ID(collectionID))
+		tracks, err = host.Provider().PlaylistTracks(ctx, collectionID)
 		if err != nil {
 			return ErrCouldNotGetPlaylistTracks
 		}
 
-		for _, item := range playlist.Items {
-			if item.Track.Track == nil {
-				log.Printf("%+v", item)
-				continue
-			}
-			if err := currentQueue.Vote(string(item.Track.Track.ID), queue.HostVoteIdentifier, item.Track.Track); err != nil {
-				return err
-			}
-		}
-
 	case "album":
-		album, err := host.Client().GetAlbumTracks(ctx, spotify.ID(collectionID))
-
+		tracks, err = host.Provider().AlbumTracks(ctx, collectionID)
 		if err != nil {
-			return ErrCouldNotGetAlbum
+			return ErrCouldNotGetAlbumTracks
 		}
 
-		ids := make([]spotify.ID, len(album.Tracks))
-		for i := 0; i < len(album.Tracks); i++ {
-			ids[i] = album.Tracks[i].ID
-		}
+	default:
+		return ErrCollectionTypeInvalid
+	}
 
-		tracks, err := host.Client().GetTracks(ctx, ids)
-		if err != nil {
-			return ErrCouldNotGetAlbumTracks
-		}
+	settings, err := s.GetSettings()
+	if err != nil {
+		return err
+	}
+	blocked, err := blockedTracks(ctx, settings.Filter, host.Provider(), tracks)
+	if err != nil {
+		return err
+	}
+	if len(blocked) > 0 {
+		log.WithField("Label", s.JamLabel).Debug("Skipped ", len(blocked), " tracks blocked by the filter")
+	}
 
-		for i := 0; i < len(tracks); i++ {
-			track, err := host.GetTrack(ctx, string(tracks[i].ID))
-			if err != nil {
-				return err
+	err = s.UpdateQueue(func(q *queue.Queue) error {
+		now := s.clock.Now()
+		for _, track := range tracks {
+			if _, ok := blocked[track.ID]; ok {
+				continue
+			}
+			if !q.ContainsRecording(track) && q.RecentlyPlayed(track, settings.Replay, now) {
+				continue
 			}
-			if err := currentQueue.Vote(string(tracks[i].ID), queue.HostVoteIdentifier, track); err != nil {
+			if err := q.Upvote(track, queue.HostVoteIdentifier); err != nil {
 				return err
 			}
 		}
-
-	default:
-		return ErrCollectionTypeInvalid
-	}
-	err = s.SetQueue(currentQueue)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
@@ -385,9 +687,12 @@ func (s *JamSession) AddCollection(ctx context.Context, collectionType string, c
 	return nil
 }
 
+// Vote flips the vote of voteID for the song. Adding a song, which isn't queued yet, is limited by the Quota
+// of the JamSession, except for the host. A *queue.QuotaError is returned, if voteID exceeded the Quota.
+// A *FilterError is returned, if the Filter of the JamSession blocks a song, which isn't queued yet, and
+// queue.ErrRecentlyPlayed, if the Replay rule of the JamSession keeps the song from being queued again.
 func (s *JamSession) Vote(ctx context.Context, songID string, voteID string) error {
 	members, err := s.GetMembers()
-	currentQueue, err := s.GetQueue()
 	if err != nil {
 		return err
 	}
@@ -395,6 +700,24 @@ func (s *JamSession) Vote(ctx context.Context, songID string, voteID string) err
 	if err != nil {
 		return err
 	}
+	settings, err := s.GetSettings()
+	if err != nil {
+		return err
+	}
+	checkQuota := func(q *queue.Queue, queued bool) error {
+		if hostMember.Identifier == voteID || queued {
+			return nil
+		}
+		return q.CheckQuota(voteID, settings.Quota, s.clock.Now())
+	}
+	// Check the quota before looking up the track, so exceeding it doesn't cost any requests
+	currentQueue, err := s.GetQueue()
+	if err != nil {
+		return err
+	}
+	if err := checkQuota(currentQueue, currentQueue.Contains(songID)); err != nil {
+		return err
+	}
 	host, err := s.hub.GetUserByIdentifier(ctx, hostMember.Identifier)
 	if err != nil {
 		return err
@@ -403,11 +726,28 @@ func (s *JamSession) Vote(ctx context.Context, songID string, voteID string) err
 	if err != nil {
 		return err
 	}
-
-	if err := currentQueue.Vote(string(track.ID), voteID, track); err != nil {
+	blocked, err := blockedTracks(ctx, settings.Filter, host.Provider(), []*provider.Track{track})
+	if err != nil {
 		return err
 	}
-	err = s.SetQueue(currentQueue)
+
+	err = s.UpdateQueue(func(q *queue.Queue) error {
+		// Votes for another release of a queued recording count for the queued song
+		queued := q.ContainsRecording(track)
+		if err := checkQuota(q, queued); err != nil {
+			return err
+		}
+		// Songs, which were queued before the filter changed, can still be voted for
+		if !queued {
+			if reason, ok := blocked[track.ID]; ok {
+				return &FilterError{Reason: reason}
+			}
+			if q.RecentlyPlayed(track, settings.Replay, s.clock.Now()) {
+				return queue.ErrRecentlyPlayed
+			}
+		}
+		return q.Vote(track.ID, voteID, track)
+	})
 	if err != nil {
 		return err
 	}
@@ -415,7 +755,7 @@ func (s *JamSession) Vote(ctx context.Context, songID string, voteID string) err
 	return nil
 }
 
-func (s *JamSession) Search(ctx context.Context, index string, searchType spotify.SearchType, options ...spotify.RequestOption) (interface{}, error) {
+func (s *JamSession) Search(ctx context.Context, index string, searchType provider.SearchType) (*provider.SearchResult, error) {
 	members, err := s.GetMembers()
 	if err != nil {
 		return nil, err
@@ -428,66 +768,168 @@ func (s *JamSession) Search(ctx context.Context, index string, searchType spotif
 	if err != nil {
 		return nil, err
 	}
-	return host.Search(ctx, index, searchType, options...)
+	return host.Search(ctx, index, searchType)
 }
 
-func (s *JamSession) IntroduceClient(conn *websocket.Conn) {
-	client := notifications.NewClient(s.room, conn)
-	client.Room.Register <- client
+// IntroduceClient connects the member identifier to the websocket notifications of the JamSession. Commands sent by
+// the client are executed on behalf of the member. A reconnecting client passes resume to catch up.
+func (s *JamSession) IntroduceClient(conn *websocket.Conn, identifier string, resume *notifications.Resume) {
+	s.introduce(notifications.NewClient(s.room, conn, identifier, false, s.handleCommand, resume))
+}
+
+// IntroduceLobbyClient connects the user identifier, who waits for approval, to the JamSession.
+// The client only receives the decision and whether the JamSession was closed.
+func (s *JamSession) IntroduceLobbyClient(conn *websocket.Conn, identifier string) {
+	s.introduce(notifications.NewClient(s.room, conn, identifier, true, nil, nil))
+}
+
+// IntroduceStreamClient connects the member identifier to the notifications of the JamSession without a websocket,
+// e.g. for server-sent events. The caller reads the messages from Send and calls Done afterwards.
+func (s *JamSession) IntroduceStreamClient(identifier string, resume *notifications.Resume) *notifications.Client {
+	client := notifications.NewStreamClient(s.room, identifier, resume)
+	s.register(client)
+	return client
+}
+
+func (s *JamSession) introduce(client *notifications.Client) {
+	s.register(client)
 
 	go client.Write()
 	go client.Read()
 }
 
+// register adds client to the room. The snapshot for a resuming client is read from the stores beforehand, so the
+// room doesn't wait for them.
+func (s *JamSession) register(client *notifications.Client) {
+	if client.Resuming() {
+		client.SetSnapshot(s.room.Snapshot(s.snapshot))
+	}
+	client.Room.Register <- client
+	// Count the member right away instead of on the next announcement
+	if !client.Lobby {
+		if err := s.cluster.Presence.Announce(s.JamLabel, []string{client.Identifier}); err != nil {
+			log.WithField("Label", s.JamLabel).Warn("Could not announce the connected member: ", err)
+		}
+	}
+}
+
 func (s *JamSession) DeleteSong(songID string) error {
-	queue, err := s.GetQueue()
+	err := s.UpdateQueue(func(q *queue.Queue) error {
+		q.Delete(songID)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	queue.Delete(songID)
-	if err := s.SetQueue(queue); err != nil {
-		return err
-	}
 	s.SocketQueueUpdate()
 	return nil
 }
 
 func (s *JamSession) SocketJamUpdate() {
-	settings, err := s.GetSettings()
+	msg, err := s.jamMessage()
 	if err != nil {
 		log.Warn("could not get settings", err)
 		return
 	}
-	s.NotifyClients(&notifications.Message{
-		Event: notifications.Jam,
-		Message: types.SocketJamMessage{
-			Label:  s.JamLabel,
-			Name:   settings.Name,
-			Active: settings.Active,
-		},
-	})
+	s.NotifyClients(msg)
+}
+
+func (s *JamSession) jamMessage() (*notifications.Message, error) {
+	settings, err := s.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	return &notifications.Message{
+		Event:   notifications.Jam,
+		Message: settings.Response(s.JamLabel),
+	}, nil
+}
+
+// Response returns the settings of the JamSession label as they are sent to the clients
+func (settings *Settings) Response(label string) types.JamResponse {
+	return types.JamResponse{
+		Label:             label,
+		Name:              settings.Name,
+		Active:            settings.Active,
+		Ordering:          string(settings.Ordering.Effective()),
+		DownvoteThreshold: settings.DownvoteThreshold,
+		SkipPercentage:    settings.SkipPercentage,
+		AutoHandover:      settings.AutoHandover,
+		RequireApproval:   settings.RequireApproval,
+		MaxPending:        settings.Quota.MaxPending,
+		MaxAdditions:      settings.Quota.MaxAdditions,
+		AdditionWindow:    int(settings.Quota.Window.Seconds()),
+		AdditionCooldown:  int(settings.Quota.Cooldown.Seconds()),
+		ReplayCooldown:    int(settings.Replay.Cooldown.Seconds()),
+		ReplaySongs:       settings.Replay.Songs,
+		LeadTime:          int(settings.EffectiveLeadTime().Milliseconds()),
+		Filter:            settings.Filter.Response(),
+		Fallback:          settings.Fallback.Response(),
+	}
 }
 
 func (s *JamSession) SocketQueueUpdate() {
-	queue, err := s.GetQueue()
+	msg, err := s.queueMessage()
 	if err != nil {
-		log.Warn("could not get settings", err)
+		log.Warn("could not get queue", err)
 		return
 	}
-	s.NotifyClients(&notifications.Message{
+	s.NotifyClients(msg)
+}
+
+func (s *JamSession) queueMessage() (*notifications.Message, error) {
+	queue, err := s.GetQueue()
+	if err != nil {
+		return nil, err
+	}
+	return &notifications.Message{
 		Event: notifications.Queue,
 		Message: types.PutQueuePlaylistsResponse{
-			Tracks: queue.Tracks(),
+			Tracks:   queue.Tracks(),
+			Fallback: queue.UpcomingFallback(),
 		},
-	})
+	}, nil
 }
 
 func (s *JamSession) SocketPlaybackUpdate(host *users.User) {
-	s.NotifyClients(&notifications.Message{
+	s.NotifyClients(playbackMessage(host))
+}
+
+func playbackMessage(host *users.User) *notifications.Message {
+	playerState := host.GetPlayerState()
+	return &notifications.Message{
 		Event: notifications.Playback,
 		Message: types.SocketPlaybackMessage{
-			Playback: host.GetPlayerState(),
-			DeviceID: host.GetPlayerState().Device.ID,
+			Playback: playerState,
+			DeviceID: playerState.Device.ID,
 		},
+	}
+}
+
+// snapshot returns the current state of the JamSession as the events, which would have brought a client up to date.
+// It is sent to clients, which missed too many events to resume.
+func (s *JamSession) snapshot() []*notifications.Message {
+	ctx := context.Background()
+	var messages []*notifications.Message
+	if msg, err := s.jamMessage(); err == nil {
+		messages = append(messages, msg)
+	}
+	if msg, err := s.queueMessage(); err == nil {
+		messages = append(messages, msg)
+	}
+	members, err := s.GetMembers()
+	if err != nil {
+		log.WithField("Label", s.JamLabel).Warn("Could not create snapshot: ", err)
+		return messages
+	}
+	messages = append(messages, &notifications.Message{
+		Event:   notifications.Members,
+		Message: members.Response(ctx, s.hub),
 	})
+	if hostMember, err := members.Host(); err == nil {
+		if host, err := s.hub.GetUserByIdentifier(ctx, hostMember.Identifier); err == nil {
+			messages = append(messages, playbackMessage(host))
+		}
+	}
+	return messages
 }
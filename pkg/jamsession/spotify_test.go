@@ -0,0 +1,257 @@
+package jamsession
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+)
+
+func TestConductorStart(t *testing.T) {
+	j := newTestJam(t)
+	song := j.track(t, "fake-track-03")
+	j.enqueue(t, "member", song)
+
+	// An inactive JamSession doesn't play anything
+	j.tick(15)
+	if playing := j.playing(t); playing != nil {
+		t.Fatalf("inactive JamSession plays %s", playing.ID)
+	}
+
+	j.update(t, func(settings *Settings) {
+		settings.Active = true
+	})
+	j.tickUntil(t, 15, func() bool {
+		return j.playing(t) != nil
+	})
+	if playing := j.playing(t); playing.ID != song.ID {
+		t.Fatalf("device plays %s, want %s", playing.ID, song.ID)
+	}
+	q := j.queue(t)
+	if q.Contains(song.ID) {
+		t.Error("the playing song is still queued")
+	}
+	if len(q.History) != 1 || q.History[0].ID != song.ID || !q.History[0].StartedAt.Equal(j.clock.Now()) {
+		t.Errorf("history = %+v, want %s started now", q.History, song.ID)
+	}
+}
+
+func TestConductorEndOfTrack(t *testing.T) {
+	j := newTestJam(t)
+	first := j.track(t, "fake-track-00")
+	second := j.track(t, "fake-track-01")
+	j.enqueue(t, "member", first, second)
+	j.enqueue(t, "other", first)
+	j.update(t, func(settings *Settings) {
+		settings.Active = true
+	})
+	j.tickUntil(t, 15, func() bool {
+		return j.playing(t) != nil
+	})
+	if playing := j.playing(t); playing.ID != first.ID {
+		t.Fatalf("device plays %s, want the most voted song %s", playing.ID, first.ID)
+	}
+	startedAt := j.clock.Now()
+
+	// The second song follows the first one without a gap and is recorded once the device reached it
+	j.tickUntil(t, 200, func() bool {
+		return len(j.queue(t).History) == 2
+	})
+	q := j.queue(t)
+	played := q.History[1]
+	if played.ID != second.ID {
+		t.Fatalf("recorded %s after the end, want %s", played.ID, second.ID)
+	}
+	if want := startedAt.Add(songDuration(first)); !played.StartedAt.Equal(want) {
+		t.Errorf("%s started at %v, want the end of the previous song at %v", second.ID, played.StartedAt, want)
+	}
+	if q.History[0].Skipped {
+		t.Error("the song, which played to its end, was recorded as skipped")
+	}
+	if len(q.Songs) != 0 {
+		t.Errorf("%d songs still queued, want none", len(q.Songs))
+	}
+	settings, err := j.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !settings.Active {
+		t.Error("reaching the handed over song deactivated the JamSession")
+	}
+
+	// Without queued songs and a fallback source the music stops after the last song
+	j.tick(int(songDuration(second).Seconds()) + 10)
+	if playing := j.playing(t); playing != nil {
+		t.Errorf("device plays %s after the last song", playing.ID)
+	}
+	if history := j.queue(t).History; len(history) != 2 {
+		t.Errorf("history has %d songs after the last song, want 2", len(history))
+	}
+}
+
+func TestConductorHostOverride(t *testing.T) {
+	j := newTestJam(t)
+	song := j.track(t, "fake-track-00")
+	j.enqueue(t, "member", song, j.track(t, "fake-track-01"))
+	j.update(t, func(settings *Settings) {
+		settings.Active = true
+	})
+	j.tickUntil(t, 15, func() bool {
+		return j.playing(t) != nil
+	})
+
+	// The host plays another song with the streaming service directly
+	override := j.track(t, "fake-track-10")
+	if err := j.device.Play(context.Background(), override); err != nil {
+		t.Fatal(err)
+	}
+	j.tickUntil(t, 15, func() bool {
+		settings, err := j.GetSettings()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return !settings.Active
+	})
+
+	// The conductor leaves the device of the host alone afterwards
+	j.tick(30)
+	if playing := j.playing(t); playing == nil || playing.ID != override.ID {
+		t.Errorf("device plays %v, want the song of the host %s", playing, override.ID)
+	}
+	q := j.queue(t)
+	if len(q.History) != 1 || q.History[0].ID != song.ID {
+		t.Errorf("history = %+v, want only %s", q.History, song.ID)
+	}
+	if len(q.Songs) != 1 {
+		t.Errorf("%d songs queued, want the remaining song", len(q.Songs))
+	}
+}
+
+func TestConductorEmptyQueue(t *testing.T) {
+	t.Run("without fallback", func(t *testing.T) {
+		j := newTestJam(t)
+		j.update(t, func(settings *Settings) {
+			settings.Active = true
+		})
+		j.tick(30)
+		if playing := j.playing(t); playing != nil {
+			t.Errorf("device plays %s with an empty queue", playing.ID)
+		}
+		if history := j.queue(t).History; len(history) != 0 {
+			t.Errorf("history has %d songs, want none", len(history))
+		}
+	})
+
+	t.Run("with fallback", func(t *testing.T) {
+		j := newTestJam(t)
+		j.update(t, func(settings *Settings) {
+			settings.Active = true
+			settings.Fallback = Fallback{Type: FallbackPlaylist, ID: "fake-playlist"}
+		})
+		j.tickUntil(t, 15, func() bool {
+			return j.playing(t) != nil
+		})
+		if playing := j.playing(t); playing.ID != "fake-track-00" {
+			t.Fatalf("device plays %s, want the first song of the playlist", playing.ID)
+		}
+		q := j.queue(t)
+		if len(q.History) != 1 || !q.History[0].Fallback {
+			t.Errorf("history = %+v, want a fallback song", q.History)
+		}
+		if len(q.Fallback) == 0 || q.Fallback[0].ID != "fake-track-01" {
+			t.Error("the playing fallback song is still next")
+		}
+
+		// A queued song replaces the song of the fallback source right away
+		song := j.track(t, "fake-track-15")
+		j.enqueue(t, "member", song)
+		j.tickUntil(t, 15, func() bool {
+			playing := j.playing(t)
+			return playing != nil && playing.ID == song.ID
+		})
+		if q := j.queue(t); q.Contains(song.ID) || q.History[len(q.History)-1].Fallback {
+			t.Error("the queued song wasn't recorded as played")
+		}
+	})
+}
+
+func TestStart(t *testing.T) {
+	j := newTestJam(t)
+	ctx := context.Background()
+	settings, err := j.GetSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Start(ctx, settings, j.host); err != queue.ErrQueueEmpty {
+		t.Fatalf("Start without songs returned %v, want %v", err, queue.ErrQueueEmpty)
+	}
+
+	// An empty queue starts with the fallback source
+	settings.Fallback = Fallback{Type: FallbackPlaylist, ID: "fake-playlist"}
+	if err := j.Start(ctx, settings, j.host); err != nil {
+		t.Fatal(err)
+	}
+	if playing := j.playing(t); playing == nil || playing.ID != "fake-track-00" {
+		t.Fatalf("device plays %v, want the first song of the playlist", playing)
+	}
+	if history := j.queue(t).History; len(history) != 1 || !history[0].Fallback {
+		t.Errorf("history = %+v, want a fallback song", history)
+	}
+
+	// Queued songs come first
+	song := j.track(t, "fake-track-15")
+	j.enqueue(t, "member", song)
+	if err := j.Start(ctx, settings, j.host); err != nil {
+		t.Fatal(err)
+	}
+	if playing := j.playing(t); playing == nil || playing.ID != song.ID {
+		t.Errorf("device plays %v, want the queued song %s", playing, song.ID)
+	}
+}
+
+// heldLeases is a Leaser, whose leases are all held by another instance
+type heldLeases struct{}
+
+func (heldLeases) Acquire(key string) (bool, error) {
+	return false, nil
+}
+
+func (heldLeases) Release(key string) error {
+	return nil
+}
+
+func TestConductorWithoutLease(t *testing.T) {
+	j := newTestJamWithLeases(t, heldLeases{})
+	song := j.track(t, "fake-track-00")
+	j.enqueue(t, "member", song)
+	j.update(t, func(settings *Settings) {
+		settings.Active = true
+	})
+
+	// The player of the host is neither controlled nor polled by this instance
+	j.tick(30)
+	if playing := j.playing(t); playing != nil {
+		t.Fatalf("instance without lease plays %s", playing.ID)
+	}
+	if err := j.device.Play(context.Background(), song); err != nil {
+		t.Fatal(err)
+	}
+	j.tick(30)
+	if state := j.host.GetPlayerState(); state.Playing {
+		t.Fatal("instance without lease polled the player of the host")
+	}
+
+	// The playback published by the conducting instance updates the player state of the host
+	state, err := j.device.PlayerState(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.deliver(&notifications.Message{
+		Event:   notifications.Playback,
+		Message: map[string]interface{}{"playback": state, "device_id": state.Device.ID},
+	})
+	if observed := j.host.GetPlayerState(); !observed.Playing || observed.Item == nil || observed.Item.ID != song.ID {
+		t.Errorf("host plays %v after the published playback, want %s", observed.Item, song.ID)
+	}
+}
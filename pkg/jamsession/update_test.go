@@ -0,0 +1,161 @@
+package jamsession_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/hub"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/lease"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/notifications"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/presence"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/sqlstore"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	"golang.org/x/oauth2"
+)
+
+// concurrentVoters is the number of members voting at the same time
+const concurrentVoters = 50
+
+func memoryStores(t *testing.T) jamsession.Stores {
+	return jamsession.Stores{
+		Members:  store.NewMemoryStore[jamsession.Members](),
+		Queues:   store.NewMemoryStore[queue.Queue](),
+		Settings: store.NewMemoryStore[jamsession.Settings](),
+	}
+}
+
+func redisStores(t *testing.T) jamsession.Stores {
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		if err := pool.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return jamsession.Stores{
+		Members:  store.NewRedisStore[jamsession.Members](pool, "jamSession:members"),
+		Queues:   store.NewRedisStore[queue.Queue](pool, "jamSession:queue"),
+		Settings: store.NewRedisStore[jamsession.Settings](pool, "jamSession:settings"),
+	}
+}
+
+func sqliteStores(t *testing.T) jamsession.Stores {
+	db, err := sqlstore.Open(sqlstore.DriverSQLite, filepath.Join(t.TempDir(), "jamfactory.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := db.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return jamsession.Stores{
+		Members:  db.Members(),
+		Queues:   db.Queues(),
+		Settings: db.Settings(),
+	}
+}
+
+// TestConcurrentVotes votes for a song through the JamSession and for another one through UpdateQueue at the same
+// time. Run it with -race. No vote may get lost.
+func TestConcurrentVotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		stores func(t *testing.T) jamsession.Stores
+	}{
+		{"memory", memoryStores},
+		{"redis", redisStores},
+		{"sqlite", sqliteStores},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			h := hub.NewHub(provider.NewFakeFactory(c), c, hub.Stores{
+				Store:       store.NewMemoryStore[users.UserInformation](),
+				Identifiers: store.NewMemorySet(),
+			})
+			host, err := h.NewUser(ctx, "host", "Host", users.UserTypeSpotify, &oauth2.Token{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			jamSession, err := jamsession.CreateNew(host, tt.stores(t), jamsession.Cluster{
+				Broker:   notifications.NewMemoryBroker(),
+				Leases:   lease.NewMemory(),
+				Presence: presence.NewMemory(c),
+			}, h, "TEST")
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() {
+				if err := jamSession.Deconstruct(); err != nil {
+					t.Error(err)
+				}
+			})
+
+			voted, err := host.GetTrack(ctx, "fake-track-00")
+			if err != nil {
+				t.Fatal(err)
+			}
+			updated, err := host.GetTrack(ctx, "fake-track-01")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 2*concurrentVoters)
+			for i := 0; i < concurrentVoters; i++ {
+				voter := fmt.Sprintf("member-%02d", i)
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					errs <- jamSession.Vote(ctx, voted.ID, voter)
+				}()
+				go func() {
+					defer wg.Done()
+					errs <- jamSession.UpdateQueue(func(q *queue.Queue) error {
+						return q.Vote(updated.ID, voter, updated)
+					})
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			q, err := jamSession.GetQueue()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, track := range []*provider.Track{voted, updated} {
+				votes := 0
+				for _, so := range q.Songs {
+					if so.ID == track.ID {
+						votes = len(so.GetVotes())
+					}
+				}
+				if votes != concurrentVoters {
+					t.Errorf("%s has %d votes, want %d", track.ID, votes, concurrentVoters)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package lease
+
+import (
+	"time"
+)
+
+const (
+	// TTL is the time after which a lease expires, if it isn't renewed
+	TTL = 15 * time.Second
+	// RenewInterval is the interval in which holders renew their leases
+	RenewInterval = TTL / 3
+)
+
+// Leaser grants exclusive, expiring leases, so only one backend instance performs work for a key.
+// A holder has to renew its lease within the TTL, otherwise another instance may take it over.
+type Leaser interface {
+	// Acquire acquires or renews the lease for key and reports whether this instance holds it
+	Acquire(key string) (bool, error)
+	// Release gives up the lease for key, if this instance holds it
+	Release(key string) error
+}
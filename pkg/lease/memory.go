@@ -0,0 +1,32 @@
+package lease
+
+import (
+	"sync"
+	"time"
+)
+
+// Memory is a Leaser for a single backend instance
+type Memory struct {
+	sync.Mutex
+	leases map[string]time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		leases: make(map[string]time.Time),
+	}
+}
+
+func (m *Memory) Acquire(key string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.leases[key] = time.Now().Add(TTL)
+	return true, nil
+}
+
+func (m *Memory) Release(key string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.leases, key)
+	return nil
+}
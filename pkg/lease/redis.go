@@ -0,0 +1,60 @@
+package lease
+
+import (
+	"github.com/gomodule/redigo/redis"
+	pkgredis "github.com/jamfactoryapp/jamfactory-backend/internal/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+// acquireScript takes the lease if it is free and renews it if it is held by the caller
+var acquireScript = redis.NewScript(1, `
+local holder = redis.call("GET", KEYS[1])
+if holder == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+if holder == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript deletes the lease if it is held by the caller
+var releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Redis is a Leaser shared by all backend instances using the same redis
+type Redis struct {
+	pool       *redis.Pool
+	redisKey   pkgredis.Key
+	instanceID string
+}
+
+func NewRedis(pool *redis.Pool, instanceID string) *Redis {
+	return &Redis{
+		pool:       pool,
+		redisKey:   pkgredis.NewKey("lease"),
+		instanceID: instanceID,
+	}
+}
+
+func (r *Redis) Acquire(key string) (bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+	held, err := redis.Bool(acquireScript.Do(conn, r.redisKey.Append(key).String(), r.instanceID, TTL.Milliseconds()))
+	log.Trace("redis lease ACQUIRE for: ", key, " held: ", held, " with err: ", err)
+	return held, err
+}
+
+func (r *Redis) Release(key string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+	_, err := releaseScript.Do(conn, r.redisKey.Append(key).String(), r.instanceID)
+	log.Trace("redis lease RELEASE for: ", key, " with err: ", err)
+	return err
+}
@@ -0,0 +1,71 @@
+package notifications
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Broker delivers messages published for a room to the subscribers of that room.
+// Every backend instance subscribes with the JamSessions it has loaded, so websocket clients
+// connected to any instance receive all messages of their JamSession.
+type Broker interface {
+	Publish(room string, msg *Message) error
+	Subscribe(room string, handler func(msg *Message)) error
+	Unsubscribe(room string) error
+}
+
+// handlers holds the local subscribers of a Broker
+type handlers struct {
+	sync.RWMutex
+	rooms map[string]func(msg *Message)
+}
+
+func (h *handlers) subscribe(room string, handler func(msg *Message)) {
+	h.Lock()
+	h.rooms[room] = handler
+	h.Unlock()
+}
+
+func (h *handlers) unsubscribe(room string) {
+	h.Lock()
+	delete(h.rooms, room)
+	h.Unlock()
+}
+
+func (h *handlers) deliver(room string, msg *Message) {
+	h.RLock()
+	handler, ok := h.rooms[room]
+	h.RUnlock()
+	if !ok {
+		log.Trace("No subscriber for room: ", room)
+		return
+	}
+	handler(msg)
+}
+
+// MemoryBroker delivers messages within a single backend instance
+type MemoryBroker struct {
+	handlers
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		handlers: handlers{rooms: make(map[string]func(msg *Message))},
+	}
+}
+
+func (b *MemoryBroker) Publish(room string, msg *Message) error {
+	b.deliver(room, msg)
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(room string, handler func(msg *Message)) error {
+	b.subscribe(room, handler)
+	return nil
+}
+
+func (b *MemoryBroker) Unsubscribe(room string) error {
+	b.unsubscribe(room)
+	return nil
+}
@@ -1,9 +1,13 @@
 package notifications
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/gorilla/websocket"
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
 	log "github.com/sirupsen/logrus"
-	"time"
 )
 
 const (
@@ -11,25 +15,136 @@ const (
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
 	maxMessageSize = 512
+	// sendBufferSize leaves room for the replies to a few commands besides the events
+	sendBufferSize = 16
+	commandTimeout = 10 * time.Second
 )
 
+// Client is the websocket connection of the user Identifier. Lobby clients wait for the approval to join and
+// only receive the messages addressed to them and Close. Commands of the client, which aren't handled by the client
+// itself, are executed by handler. Lobby clients can only ping. A reconnecting client passes resume to receive the
+// messages it missed.
 type Client struct {
-	Room *Room
-	Conn *websocket.Conn
-	Send chan *Message
+	Room       *Room
+	Conn       *websocket.Conn
+	Send       chan *Message
+	Identifier string
+	Lobby      bool
+	handler    CommandHandler
+	resume     *Resume
+	snapshot   *Snapshot
+	events     map[WebsocketEvent]bool
+	eventsLock sync.RWMutex
+	// lagging is set by the room before closing Send, if the client couldn't keep up
+	lagging bool
 }
 
-func NewClient(room *Room, conn *websocket.Conn) *Client {
+func NewClient(room *Room, conn *websocket.Conn, identifier string, lobby bool, handler CommandHandler, resume *Resume) *Client {
+	room.writers.Add(1)
 	return &Client{
-		Room: room,
-		Conn: conn,
-		Send: make(chan *Message, 1),
+		Room:       room,
+		Conn:       conn,
+		Send:       make(chan *Message, sendBufferSize),
+		Identifier: identifier,
+		Lobby:      lobby,
+		handler:    handler,
+		resume:     resume,
+	}
+}
+
+// Resuming reports whether the client needs a snapshot, in case it missed too many messages to resume
+func (c *Client) Resuming() bool {
+	return c.resume != nil && !c.Lobby
+}
+
+// SetSnapshot sets the snapshot sent to a resuming client, which missed too many messages. It has to be set before
+// the client is registered.
+func (c *Client) SetSnapshot(snapshot *Snapshot) {
+	c.snapshot = snapshot
+}
+
+// receives reports whether msg is sent to the client
+func (c *Client) receives(msg *Message) bool {
+	if msg.client != nil {
+		return msg.client == c
+	}
+	if msg.Recipient != "" {
+		return msg.Recipient == c.Identifier
+	}
+	if c.Lobby {
+		return msg.Event == Close
+	}
+	return c.subscribed(msg.Event)
+}
+
+// subscribed reports whether the client subscribed to event. Without a subscription all events are sent.
+func (c *Client) subscribed(event WebsocketEvent) bool {
+	if !subscribable[event] {
+		return true
+	}
+	c.eventsLock.RLock()
+	defer c.eventsLock.RUnlock()
+	return c.events == nil || c.events[event]
+}
+
+// subscribe limits the events sent to the client to events. No events subscribe to all events
+func (c *Client) subscribe(events []string) error {
+	var subscription map[WebsocketEvent]bool
+	if len(events) > 0 {
+		subscription = make(map[WebsocketEvent]bool, len(events))
+		for _, event := range events {
+			if !subscribable[WebsocketEvent(event)] {
+				return &CommandError{Code: CodeInvalid, Err: ErrEventUnknown}
+			}
+			subscription[WebsocketEvent(event)] = true
+		}
 	}
+	c.eventsLock.Lock()
+	c.events = subscription
+	c.eventsLock.Unlock()
+	return nil
+}
+
+// execute runs cmd and returns the result sent to the client with the Ack
+func (c *Client) execute(cmd *Command) (interface{}, error) {
+	switch cmd.Type {
+	case CommandPing:
+		return types.SocketPongMessage{Time: time.Now().UnixMilli()}, nil
+	case CommandSubscribe:
+		if c.Lobby {
+			return nil, &CommandError{Code: CodeForbidden, Err: ErrCommandForbidden}
+		}
+		var payload types.SocketSubscribeCommand
+		if err := cmd.DecodePayload(&payload); err != nil {
+			return nil, err
+		}
+		if err := c.subscribe(payload.Events); err != nil {
+			return nil, err
+		}
+		return types.SocketSubscribedMessage{Events: payload.Events}, nil
+	default:
+		if c.Lobby || c.handler == nil {
+			return nil, &CommandError{Code: CodeForbidden, Err: ErrCommandForbidden}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		return c.handler(ctx, c.Identifier, cmd)
+	}
+}
+
+// reply sends msg only to the client
+func (c *Client) reply(msg *Message) {
+	msg.client = c
+	c.Room.Send(msg)
 }
 
 func (c *Client) Read() {
 	defer func() {
-		c.Room.Unregister <- c
+		// The room doesn't unregister clients anymore, once its doors were closed
+		select {
+		case c.Room.Unregister <- c:
+		case <-c.Room.done:
+		}
 		if err := c.Conn.Close(); err != nil {
 			log.Trace("Error closing connection: ", err)
 		}
@@ -52,12 +167,18 @@ func (c *Client) Read() {
 			}
 			break
 		}
-		message := &Message{}
-		if err := message.Deserialize(data); err != nil {
-			log.Error("Failed to deserialize message: ", err)
-			break
+		// Commands are answered only to the client, nothing sent by a client reaches the other members
+		cmd, err := ParseCommand(data)
+		if err == nil {
+			var result interface{}
+			result, err = c.execute(cmd)
+			if err == nil {
+				c.reply(&Message{Event: Ack, RequestID: cmd.ID, Message: result})
+				continue
+			}
 		}
-		c.Room.Broadcast <- message
+		log.WithField("Identifier", c.Identifier).Debug("Command failed: ", err)
+		c.reply(errorMessage(cmd.ID, err))
 	}
 }
 
@@ -68,6 +189,7 @@ func (c *Client) Write() {
 		if err := c.Conn.Close(); err != nil {
 			log.Trace("Error closing connection: ", err)
 		}
+		c.Room.writers.Done()
 	}()
 	for {
 		select {
@@ -76,7 +198,12 @@ func (c *Client) Write() {
 				log.Trace("Error setting write deadline: ", err)
 			}
 			if !ok {
-				if err := c.Conn.WriteMessage(websocket.CloseMessage, []byte{}); err != nil {
+				data := []byte{}
+				if c.lagging {
+					// Tell the client to reconnect and resume
+					data = websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "lagging")
+				}
+				if err := c.Conn.WriteMessage(websocket.CloseMessage, data); err != nil {
 					log.Trace("Error writing message: ", err)
 				}
 				return
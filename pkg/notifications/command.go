@@ -0,0 +1,125 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+)
+
+// ProtocolVersion is the version of the websocket protocol. Commands of other versions are rejected
+const ProtocolVersion = 1
+
+const maxCommandIDLength = 64
+
+var (
+	ErrCommandInvalid   = errors.New("invalid command")
+	ErrVersionInvalid   = errors.New("unsupported protocol version")
+	ErrCommandUnknown   = errors.New("unknown command")
+	ErrCommandForbidden = errors.New("command not allowed")
+	ErrEventUnknown     = errors.New("unknown event")
+)
+
+// CommandType is the kind of a Command sent by a client
+type CommandType string
+
+const (
+	CommandVote      CommandType = "vote"
+	CommandSkipVote  CommandType = "skip_vote"
+	CommandPing      CommandType = "ping"
+	CommandSubscribe CommandType = "subscribe"
+)
+
+// ErrorCode tells clients, why a command failed
+type ErrorCode string
+
+const (
+	CodeInvalid     ErrorCode = "invalid"
+	CodeVersion     ErrorCode = "unsupported_version"
+	CodeUnknown     ErrorCode = "unknown_command"
+	CodeForbidden   ErrorCode = "forbidden"
+	CodeNotFound    ErrorCode = "not_found"
+	CodeRateLimited ErrorCode = "rate_limited"
+	CodeInternal    ErrorCode = "internal"
+)
+
+// Command is sent by a client. The Ack or Error replying to it carries its ID.
+type Command struct {
+	Version int             `json:"version"`
+	ID      string          `json:"id"`
+	Type    CommandType     `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// CommandHandler executes the commands of the member identifier, which aren't handled by the client itself.
+// The result is sent back to the client with the Ack. A *CommandError tells the client, why the command failed.
+type CommandHandler func(ctx context.Context, identifier string, cmd *Command) (interface{}, error)
+
+// CommandError is returned by a CommandHandler for errors, which are shown to the client
+type CommandError struct {
+	Code       ErrorCode
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *CommandError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ParseCommand decodes and validates a Command. The returned Command carries the ID even if it is invalid, so the
+// error can be correlated.
+func ParseCommand(data []byte) (*Command, error) {
+	cmd := &Command{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cmd); err != nil {
+		return &Command{}, &CommandError{Code: CodeInvalid, Err: ErrCommandInvalid}
+	}
+	if cmd.ID == "" || len(cmd.ID) > maxCommandIDLength {
+		return &Command{}, &CommandError{Code: CodeInvalid, Err: ErrCommandInvalid}
+	}
+	if cmd.Version != ProtocolVersion {
+		return cmd, &CommandError{Code: CodeVersion, Err: ErrVersionInvalid}
+	}
+	switch cmd.Type {
+	case CommandVote, CommandSkipVote, CommandPing, CommandSubscribe:
+		return cmd, nil
+	default:
+		return cmd, &CommandError{Code: CodeUnknown, Err: ErrCommandUnknown}
+	}
+}
+
+// DecodePayload decodes the payload of the command into v. Unknown fields are rejected.
+func (cmd *Command) DecodePayload(v interface{}) error {
+	if len(cmd.Payload) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(cmd.Payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return &CommandError{Code: CodeInvalid, Err: ErrCommandInvalid}
+	}
+	return nil
+}
+
+// errorMessage returns the reply to the command id, which failed with err. Only the errors of a CommandError are
+// shown to clients.
+func errorMessage(id string, err error) *Message {
+	reply := types.SocketErrorMessage{Code: string(CodeInternal), Error: "internal error"}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		reply.Code = string(cmdErr.Code)
+		reply.Error = cmdErr.Error()
+		if cmdErr.RetryAfter > 0 {
+			reply.RetryAfter = int((cmdErr.RetryAfter + time.Second - 1) / time.Second)
+		}
+	}
+	return &Message{Event: Error, RequestID: id, Message: reply}
+}
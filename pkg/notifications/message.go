@@ -5,9 +5,19 @@ import (
 	"encoding/json"
 )
 
+// Message is sent to all clients of a room. If Recipient is set, it is only sent to the clients of that user.
+// Replies to a Command carry its RequestID and are only sent to the client, which sent the command. All other messages
+// are numbered by Seq in the order the room sends them. If Disconnect is set, the clients of that user are
+// disconnected after the message was sent to them.
 type Message struct {
-	Event   WebsocketEvent `json:"event"`
-	Message interface{}    `json:"message"`
+	Version    int            `json:"version"`
+	Seq        uint64         `json:"seq,omitempty"`
+	Event      WebsocketEvent `json:"event"`
+	Message    interface{}    `json:"message"`
+	Recipient  string         `json:"recipient,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Disconnect string         `json:"disconnect,omitempty"`
+	client     *Client
 }
 
 func (m *Message) Serialize() ([]byte, error) {
@@ -35,12 +45,33 @@ const (
 	Close                   = "close"
 	Jam                     = "jam"
 	Members                 = "members"
+	Skip                    = "skip"
+	Kick                    = "kick"
+	Lobby                   = "lobby"
+	Approval                = "approval"
+	Ack                     = "ack"
+	Error                   = "error"
+	Hello                   = "hello"
+	// Activity is only exchanged between backend instances and never sent to clients
+	Activity = "activity"
 )
 
+// subscribable are the events, which clients can subscribe to. Replies, approvals and Close are always sent.
+var subscribable = map[WebsocketEvent]bool{
+	Playback: true,
+	Queue:    true,
+	Jam:      true,
+	Members:  true,
+	Skip:     true,
+	Kick:     true,
+	Lobby:    true,
+}
+
 type WebsocketCloseType string
 
 const (
-	HostLeft WebsocketCloseType = "host"
-	Inactive                    = "inactive"
-	Warning                     = "warning"
+	HostLeft   WebsocketCloseType = "host"
+	Inactive                      = "inactive"
+	Warning                       = "warning"
+	Restarting                    = "restarting"
 )
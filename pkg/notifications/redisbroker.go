@@ -0,0 +1,84 @@
+package notifications
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	pkgredis "github.com/jamfactoryapp/jamfactory-backend/internal/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+const reconnectWait = time.Second
+
+// RedisBroker fans messages out to all backend instances using redis pub/sub.
+// Each instance holds a single pattern subscription and delivers messages to its local subscribers.
+type RedisBroker struct {
+	handlers
+	pool    *redis.Pool
+	channel pkgredis.Key
+}
+
+func NewRedisBroker(pool *redis.Pool) *RedisBroker {
+	b := &RedisBroker{
+		handlers: handlers{rooms: make(map[string]func(msg *Message))},
+		pool:     pool,
+		channel:  pkgredis.NewKey("notifications"),
+	}
+	go b.listen()
+	return b
+}
+
+func (b *RedisBroker) Publish(room string, msg *Message) error {
+	data, err := msg.Serialize()
+	if err != nil {
+		return err
+	}
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("PUBLISH", b.channel.Append(room).String(), data)
+	log.Trace("redis DO PUBLISH for: ", room, " with err: ", err)
+	return err
+}
+
+func (b *RedisBroker) Subscribe(room string, handler func(msg *Message)) error {
+	b.subscribe(room, handler)
+	return nil
+}
+
+func (b *RedisBroker) Unsubscribe(room string) error {
+	b.unsubscribe(room)
+	return nil
+}
+
+// listen receives the messages of all rooms and reconnects if the subscription fails
+func (b *RedisBroker) listen() {
+	for {
+		if err := b.receive(); err != nil {
+			log.Warn("Notification subscription failed, reconnecting: ", err)
+		}
+		time.Sleep(reconnectWait)
+	}
+}
+
+func (b *RedisBroker) receive() error {
+	conn := redis.PubSubConn{Conn: b.pool.Get()}
+	defer conn.Close()
+	prefix := b.channel.String() + ":"
+	if err := conn.PSubscribe(prefix + "*"); err != nil {
+		return err
+	}
+	for {
+		switch reply := conn.Receive().(type) {
+		case redis.Message:
+			msg := &Message{}
+			if err := msg.Deserialize(reply.Data); err != nil {
+				log.Error("Failed to deserialize message: ", err)
+				continue
+			}
+			b.deliver(strings.TrimPrefix(reply.Channel, prefix), msg)
+		case error:
+			return reply
+		}
+	}
+}
@@ -1,16 +1,50 @@
 package notifications
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
 	log "github.com/sirupsen/logrus"
 )
 
+// historySize is the number of recent messages kept for clients resuming their connection
+const historySize = 128
+
+// Resume is passed by a reconnecting client. Stream and Seq are taken from the last message it received.
+type Resume struct {
+	Stream string
+	Seq    uint64
+}
+
+// Snapshot is the current state as messages, for clients, which missed too many messages to resume. Seq is the
+// sequence number of the room before the state was read, so the messages recorded since are sent after the snapshot.
+type Snapshot struct {
+	Seq      uint64
+	Messages []*Message
+}
+
+// Room sends the messages of a JamSession to its clients on this backend instance. The messages are numbered per
+// stream, which is unique to the room, so clients reconnecting to another instance start over with a snapshot.
 type Room struct {
 	Clients    map[*Client]bool
 	Broadcast  chan *Message
 	Register   chan *Client
 	Unregister chan *Client
 	quit       chan bool
+	done       chan struct{}
+	writers    sync.WaitGroup
 	log        *log.Entry
+	stream     string
+	seq        uint64
+	history    []*Message
+	playback   *Message
+	// connected counts the clients of each user, who isn't waiting in the lobby
+	connected     map[string]int
+	connectedLock sync.RWMutex
 }
 
 func NewRoom() *Room {
@@ -19,7 +53,11 @@ func NewRoom() *Room {
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Clients:    make(map[*Client]bool),
+		connected:  make(map[string]int),
 		quit:       make(chan bool),
+		done:       make(chan struct{}),
+		stream:     newStreamID(),
+		history:    make([]*Message, 0, historySize),
 	}
 }
 
@@ -27,33 +65,195 @@ func (r *Room) OpenDoors() {
 	for {
 		select {
 		case <-r.quit:
+			for client := range r.Clients {
+				r.remove(client)
+			}
+			close(r.done)
 			return
 		case client := <-r.Register:
 			log.Trace("Registered client: ", client)
-			r.Clients[client] = true
+			r.add(client)
+			r.welcome(client)
 		case client := <-r.Unregister:
 			log.Trace("Unregistered client: ", client)
 			if _, ok := r.Clients[client]; ok {
-				delete(r.Clients, client)
-				close(client.Send)
+				r.remove(client)
 			}
 		case message := <-r.Broadcast:
 			log.Trace("Broadcasting message: ", message)
+			message.Version = ProtocolVersion
+			if message.client == nil {
+				r.record(message)
+			}
 			for client := range r.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(r.Clients, client)
+				if client.receives(message) {
+					r.deliver(client, message)
 				}
 			}
+			if message.Disconnect != "" {
+				r.disconnect(message.Disconnect)
+			}
 		}
 	}
 }
 
-func (r *Room) CloseDoors() {
+// record numbers message and keeps it for resuming clients. Playback messages carry the whole playback state and are
+// sent every second, so they aren't numbered and only the latest one is kept instead of filling up the history.
+func (r *Room) record(message *Message) {
+	if message.Event == Playback {
+		r.playback = message
+		return
+	}
+	message.Seq = atomic.AddUint64(&r.seq, 1)
+	if len(r.history) == historySize {
+		copy(r.history, r.history[1:])
+		r.history = r.history[:historySize-1]
+	}
+	r.history = append(r.history, message)
+}
+
+// welcome sends the hello message to a new client. A resuming client receives the messages it missed or its
+// snapshot followed by the messages recorded since, before it receives any new message.
+func (r *Room) welcome(client *Client) {
+	hello := types.SocketHelloMessage{Stream: r.stream, Seq: r.seq}
+	if client.resume != nil {
+		if missed, ok := r.missed(client); ok {
+			hello.Resumed = true
+			hello.Missed = missed
+		} else if client.snapshot != nil {
+			for _, message := range client.snapshot.Messages {
+				message.Version = ProtocolVersion
+				hello.Snapshot = append(hello.Snapshot, message)
+			}
+			if since, ok := r.since(client, client.snapshot.Seq); ok {
+				hello.Snapshot = append(hello.Snapshot, since...)
+			}
+		}
+	}
+	client.snapshot = nil
+	r.deliver(client, &Message{Version: ProtocolVersion, Event: Hello, Message: hello})
+}
+
+// missed returns the messages for client after the sequence number it resumes from and the latest playback. ok is
+// false, if they aren't kept anymore or the client resumes another stream.
+func (r *Room) missed(client *Client) (missed []interface{}, ok bool) {
+	if client.resume.Stream != r.stream {
+		return nil, false
+	}
+	return r.since(client, client.resume.Seq)
+}
+
+// since returns the messages for client after the sequence number seq and the latest playback. ok is false, if they
+// aren't kept anymore.
+func (r *Room) since(client *Client, seq uint64) (messages []interface{}, ok bool) {
+	if seq > r.seq || r.seq-seq > uint64(len(r.history)) {
+		return nil, false
+	}
+	messages = make([]interface{}, 0)
+	for _, message := range r.history[len(r.history)-int(r.seq-seq):] {
+		if client.receives(message) {
+			messages = append(messages, message)
+		}
+	}
+	if r.playback != nil && client.receives(r.playback) {
+		messages = append(messages, r.playback)
+	}
+	return messages, true
+}
+
+// Snapshot creates the snapshot for a client from the messages returned by state. It is called before the client is
+// registered, so reading the state doesn't hold up the room.
+func (r *Room) Snapshot(state func() []*Message) *Snapshot {
+	seq := atomic.LoadUint64(&r.seq)
+	return &Snapshot{Seq: seq, Messages: state()}
+}
+
+// deliver sends message to client. Clients, which can't keep up, are disconnected and can resume afterwards.
+func (r *Room) deliver(client *Client, message *Message) {
+	select {
+	case client.Send <- message:
+	default:
+		log.WithField("Identifier", client.Identifier).Debug("Disconnecting lagging client")
+		client.lagging = true
+		r.remove(client)
+	}
+}
+
+func (r *Room) add(client *Client) {
+	r.Clients[client] = true
+	if !client.Lobby {
+		r.connectedLock.Lock()
+		r.connected[client.Identifier]++
+		r.connectedLock.Unlock()
+	}
+}
+
+// remove removes client from the room and closes its Send channel
+func (r *Room) remove(client *Client) {
+	delete(r.Clients, client)
+	close(client.Send)
+	if !client.Lobby {
+		r.connectedLock.Lock()
+		if r.connected[client.Identifier]--; r.connected[client.Identifier] <= 0 {
+			delete(r.connected, client.Identifier)
+		}
+		r.connectedLock.Unlock()
+	}
+}
+
+// Connected returns the users connected to the room on this backend instance with a client, which doesn't wait in
+// the lobby
+func (r *Room) Connected() []string {
+	r.connectedLock.RLock()
+	defer r.connectedLock.RUnlock()
+	identifiers := make([]string, 0, len(r.connected))
+	for identifier := range r.connected {
+		identifiers = append(identifiers, identifier)
+	}
+	return identifiers
+}
+
+// disconnect closes the clients of the user identifier, after they received the pending messages
+func (r *Room) disconnect(identifier string) {
 	for client := range r.Clients {
-		r.Unregister <- client
+		if client.Identifier == identifier {
+			log.WithField("Identifier", identifier).Debug("Disconnecting client")
+			r.remove(client)
+		}
 	}
+}
+
+// Send broadcasts msg to all clients of the room. It doesn't block after the doors were closed
+func (r *Room) Send(msg *Message) {
+	select {
+	case r.Broadcast <- msg:
+	case <-r.done:
+	}
+}
+
+func (r *Room) CloseDoors() {
 	r.quit <- true
 }
+
+// Wait waits until all clients of the room have written their remaining messages and closed the connection
+func (r *Room) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		r.writers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newStreamID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		log.Warn("Could not create stream ID: ", err)
+	}
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,76 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+)
+
+// streamEvents are the events sent to event stream clients
+var streamEvents = []string{string(Playback), Queue, Jam, Members, Skip, Kick}
+
+// NewStreamClient creates a client for the user identifier, which reads its messages from Send instead of a
+// websocket connection, e.g. for a server-sent events stream. It only receives the playback, queue, jam, members,
+// skip, kick and close events and can't send commands. Without resume, the hello message contains a snapshot, as
+// stream clients can't request the state otherwise. Done has to be called, once the client stopped reading.
+func NewStreamClient(room *Room, identifier string, resume *Resume) *Client {
+	if resume == nil {
+		resume = &Resume{}
+	}
+	client := NewClient(room, nil, identifier, false, nil, resume)
+	// The events are known, so this can't fail
+	_ = client.subscribe(streamEvents)
+	return client
+}
+
+// Done unregisters a stream client from the room
+func (c *Client) Done() {
+	select {
+	case c.Room.Unregister <- c:
+	case <-c.Room.done:
+	}
+	c.Room.writers.Done()
+}
+
+// Lagging reports whether the room closed Send, because the client couldn't keep up
+func (c *Client) Lagging() bool {
+	return c.lagging
+}
+
+// WriteEvent writes msg in the format of server-sent events. The ID consists of the stream and the sequence number,
+// so a reconnecting client can pass it as Last-Event-ID to resume.
+func (c *Client) WriteEvent(w io.Writer, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	seq := msg.Seq
+	if hello, ok := msg.Message.(types.SocketHelloMessage); ok {
+		seq = hello.Seq
+	}
+	if msg.Seq > 0 || msg.Event == Hello {
+		if _, err := fmt.Fprintf(w, "id: %s:%d\n", c.Room.stream, seq); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Event, data)
+	return err
+}
+
+// ParseEventID parses the ID of a server-sent event passed by a reconnecting client. Unknown IDs resume nothing, so
+// the client receives a snapshot.
+func ParseEventID(id string) *Resume {
+	stream, seq, ok := strings.Cut(id, ":")
+	if !ok {
+		return &Resume{}
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return &Resume{}
+	}
+	return &Resume{Stream: stream, Seq: n}
+}
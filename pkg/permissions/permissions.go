@@ -5,13 +5,34 @@ type Permission string
 type Permissions []Permission
 
 const (
+	// Guest is granted to members, who joined a JamSession. It implies Vote and AddSong
 	Guest Permission = "Guest"
-	Host             = "Host"
+	// Host is granted to the single host of a JamSession. It implies all other permissions
+	Host            = "Host"
+	Vote            = "Vote"
+	AddSong         = "AddSong"
+	AddCollection   = "AddCollection"
+	DeleteSong      = "DeleteSong"
+	ControlPlayback = "ControlPlayback"
+	ManageMembers   = "ManageMembers"
+	ChangeSettings  = "ChangeSettings"
 )
 
 var valid = map[Permission]struct{}{
-	Guest: {},
-	Host:  {},
+	Guest:           {},
+	Host:            {},
+	Vote:            {},
+	AddSong:         {},
+	AddCollection:   {},
+	DeleteSong:      {},
+	ControlPlayback: {},
+	ManageMembers:   {},
+	ChangeSettings:  {},
+}
+
+var implied = map[Permission]Permissions{
+	Guest: {Vote, AddSong},
+	Host:  {Guest, Vote, AddSong, AddCollection, DeleteSong, ControlPlayback, ManageMembers, ChangeSettings},
 }
 
 func (p Permission) Valid() bool {
@@ -27,3 +48,42 @@ func (p Permissions) Valid() bool {
 	}
 	return true
 }
+
+// Grants reports whether p contains all required permissions, either directly or implied by Guest or Host
+func (p Permissions) Grants(required ...Permission) bool {
+	granted := make(map[Permission]struct{})
+	for _, perm := range p {
+		granted[perm] = struct{}{}
+		for _, impliedPerm := range implied[perm] {
+			granted[impliedPerm] = struct{}{}
+		}
+	}
+	for _, toCheck := range required {
+		if _, ok := granted[toCheck]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether p and other contain the same permissions, regardless of their order
+func (p Permissions) Equal(other Permissions) bool {
+	set, otherSet := p.set(), other.set()
+	if len(set) != len(otherSet) {
+		return false
+	}
+	for perm := range otherSet {
+		if _, ok := set[perm]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Permissions) set() map[Permission]struct{} {
+	set := make(map[Permission]struct{}, len(p))
+	for _, perm := range p {
+		set[perm] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,42 @@
+package permissions
+
+// Role is a named set of permissions, which can be assigned to a member at once
+type Role string
+
+const (
+	RoleListener  Role = "listener"
+	RoleGuest     Role = "guest"
+	RoleDJ        Role = "dj"
+	RoleModerator Role = "moderator"
+	RoleCoHost    Role = "cohost"
+	RoleHost      Role = "host"
+)
+
+var roles = map[Role]Permissions{
+	RoleListener:  {},
+	RoleGuest:     {Guest},
+	RoleDJ:        {Guest, AddCollection, ControlPlayback},
+	RoleModerator: {Guest, DeleteSong, ManageMembers},
+	RoleCoHost:    {Guest, AddCollection, DeleteSong, ControlPlayback, ManageMembers, ChangeSettings},
+	RoleHost:      {Guest, Host},
+}
+
+func (r Role) Valid() bool {
+	_, ok := roles[r]
+	return ok
+}
+
+// Permissions returns a copy of the permissions of the role
+func (r Role) Permissions() Permissions {
+	return append(Permissions{}, roles[r]...)
+}
+
+// RoleOf returns the role with exactly the permissions p, or an empty role if there is none
+func RoleOf(p Permissions) Role {
+	for role, rolePermissions := range roles {
+		if p.Equal(rolePermissions) {
+			return role
+		}
+	}
+	return ""
+}
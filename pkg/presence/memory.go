@@ -0,0 +1,55 @@
+package presence
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+)
+
+// Memory is a Tracker for a single backend instance
+type Memory struct {
+	sync.Mutex
+	clock clock.Clock
+	rooms map[string]map[string]time.Time
+}
+
+func NewMemory(c clock.Clock) *Memory {
+	return &Memory{
+		clock: c,
+		rooms: make(map[string]map[string]time.Time),
+	}
+}
+
+func (m *Memory) Announce(room string, identifiers []string) error {
+	m.Lock()
+	defer m.Unlock()
+	users, ok := m.rooms[room]
+	if !ok {
+		users = make(map[string]time.Time)
+		m.rooms[room] = users
+	}
+	expiry := m.clock.Now().Add(TTL)
+	for _, identifier := range identifiers {
+		users[identifier] = expiry
+	}
+	return nil
+}
+
+func (m *Memory) Connected(room string) (map[string]bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	now := m.clock.Now()
+	connected := make(map[string]bool)
+	for identifier, expiry := range m.rooms[room] {
+		if now.Before(expiry) {
+			connected[identifier] = true
+		} else {
+			delete(m.rooms[room], identifier)
+		}
+	}
+	if len(connected) == 0 {
+		delete(m.rooms, room)
+	}
+	return connected, nil
+}
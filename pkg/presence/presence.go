@@ -0,0 +1,21 @@
+package presence
+
+import (
+	"time"
+)
+
+const (
+	// TTL is the time after which an announced user counts as disconnected, if the announcement isn't renewed
+	TTL = 15 * time.Second
+	// RenewInterval is the interval in which instances announce the users connected to them again
+	RenewInterval = TTL / 3
+)
+
+// Tracker shares the users connected to a room between the backend instances. Every instance announces the users
+// connected to it within the TTL, so the users of a failed instance disappear on their own.
+type Tracker interface {
+	// Announce marks the users identifiers as connected to room until the TTL expires
+	Announce(room string, identifiers []string) error
+	// Connected returns the users connected to room through any instance
+	Connected(room string) (map[string]bool, error)
+}
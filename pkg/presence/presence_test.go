@@ -0,0 +1,84 @@
+package presence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+)
+
+func TestTracker(t *testing.T) {
+	tests := []struct {
+		name string
+		// trackers returns the trackers of two instances
+		trackers func(t *testing.T, c clock.Clock) (Tracker, Tracker)
+	}{
+		{"memory", func(t *testing.T, c clock.Clock) (Tracker, Tracker) {
+			tracker := NewMemory(c)
+			return tracker, tracker
+		}},
+		{"redis", func(t *testing.T, c clock.Clock) (Tracker, Tracker) {
+			server := miniredis.RunT(t)
+			pool := &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return redis.Dial("tcp", server.Addr())
+				},
+			}
+			t.Cleanup(func() {
+				if err := pool.Close(); err != nil {
+					t.Error(err)
+				}
+			})
+			return NewRedis(pool, c), NewRedis(pool, c)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			first, second := tt.trackers(t, c)
+
+			if err := first.Announce("room", []string{"a", "b"}); err != nil {
+				t.Fatal(err)
+			}
+			c.Advance(RenewInterval)
+			if err := second.Announce("room", []string{"b", "c"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := second.Announce("other", []string{"d"}); err != nil {
+				t.Fatal(err)
+			}
+			assertConnected(t, first, "a", "b", "c")
+
+			// The announcements of the first instance expire, while the second one keeps renewing b
+			c.Advance(TTL - RenewInterval)
+			assertConnected(t, first, "b", "c")
+			if err := second.Announce("room", []string{"b"}); err != nil {
+				t.Fatal(err)
+			}
+			c.Advance(RenewInterval)
+			assertConnected(t, first, "b")
+			c.Advance(TTL)
+			assertConnected(t, first)
+		})
+	}
+}
+
+func assertConnected(t *testing.T, tracker Tracker, identifiers ...string) {
+	t.Helper()
+	connected, err := tracker.Connected("room")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(connected) != len(identifiers) {
+		t.Errorf("connected = %v, want %v", connected, identifiers)
+		return
+	}
+	for _, identifier := range identifiers {
+		if !connected[identifier] {
+			t.Errorf("connected = %v, want %v", connected, identifiers)
+			return
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package presence
+
+import (
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+	pkgredis "github.com/jamfactoryapp/jamfactory-backend/internal/redis"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	log "github.com/sirupsen/logrus"
+)
+
+// Redis is a Tracker shared by all backend instances using the same redis. The users of a room are kept in a sorted
+// set scored by the expiry of their latest announcement.
+type Redis struct {
+	pool     *redis.Pool
+	clock    clock.Clock
+	redisKey pkgredis.Key
+}
+
+func NewRedis(pool *redis.Pool, c clock.Clock) *Redis {
+	return &Redis{
+		pool:     pool,
+		clock:    c,
+		redisKey: pkgredis.NewKey("presence"),
+	}
+}
+
+func (r *Redis) Announce(room string, identifiers []string) error {
+	if len(identifiers) == 0 {
+		return nil
+	}
+	conn := r.pool.Get()
+	defer conn.Close()
+	key := r.redisKey.Append(room).String()
+	// Another instance may have announced a user later, so only later expiries replace the score
+	now := r.clock.Now()
+	args := redis.Args{}.Add(key, "GT")
+	expiry := now.Add(TTL).UnixMilli()
+	for _, identifier := range identifiers {
+		args = args.Add(expiry, identifier)
+	}
+	if err := conn.Send("ZADD", args...); err != nil {
+		return err
+	}
+	// Drop the users, which disconnected
+	if err := conn.Send("ZREMRANGEBYSCORE", key, "-inf", now.UnixMilli()); err != nil {
+		return err
+	}
+	// The room disappears with the last announcement
+	if err := conn.Send("PEXPIRE", key, TTL.Milliseconds()); err != nil {
+		return err
+	}
+	_, err := conn.Do("")
+	log.Trace("redis presence ANNOUNCE for: ", room, " with err: ", err)
+	return err
+}
+
+func (r *Redis) Connected(room string) (map[string]bool, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+	// Only users with an expiry after now are connected
+	after := "(" + strconv.FormatInt(r.clock.Now().UnixMilli(), 10)
+	identifiers, err := redis.Strings(conn.Do("ZRANGEBYSCORE", r.redisKey.Append(room).String(), after, "+inf"))
+	log.Trace("redis presence CONNECTED for: ", room, " with err: ", err)
+	if err != nil {
+		return nil, err
+	}
+	connected := make(map[string]bool, len(identifiers))
+	for _, identifier := range identifiers {
+		connected[identifier] = true
+	}
+	return connected, nil
+}
@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	"golang.org/x/oauth2"
+)
+
+const (
+	fakeDeviceID     = "fake-device"
+	fakePlaylistID   = "fake-playlist"
+	fakeAlbumID      = "fake-album"
+	fakeCatalogSize  = 20
+	fakeTrackSeconds = 180
+)
+
+// Fake is an in-memory MusicProvider. It serves a small generated catalog and simulates
+// a single playback device, so JamFactory can be run and tested without a streaming service.
+// Playback progresses with the clock, so a clock.Fake makes songs end on demand.
+type Fake struct {
+	sync.Mutex
+	clock     clock.Clock
+	catalog   []*Track
+	genres    map[string][]string
+	playlists map[string][]*Track
+	device    Device
+	item      *Track
+	next      *Track
+	playing   bool
+	progress  int
+	startedAt time.Time
+}
+
+func NewFake(c clock.Clock) *Fake {
+	catalog := make([]*Track, fakeCatalogSize)
+	album := Album{
+		ID:      fakeAlbumID,
+		URI:     "fake:album:" + fakeAlbumID,
+		Name:    "Fake Album",
+		Artists: []Artist{{ID: "fake-artist", URI: "fake:artist:fake-artist", Name: "Fake Artist"}},
+	}
+	for i := range catalog {
+		id := fmt.Sprintf("fake-track-%02d", i)
+		// The last track is another release of the first recording
+		recording := i % (fakeCatalogSize - 1)
+		catalog[i] = &Track{
+			ID:       id,
+			URI:      "fake:track:" + id,
+			Name:     fmt.Sprintf("Fake Track %02d", i),
+			Artists:  album.Artists,
+			Album:    album,
+			Duration: (fakeTrackSeconds + i) * 1000,
+			Explicit: i%5 == 4,
+			ISRC:     fmt.Sprintf("XXFAK%07d", recording),
+		}
+	}
+	return &Fake{
+		clock:   c,
+		catalog: catalog,
+		genres: map[string][]string{
+			"fake-artist": {"pop", "dance pop"},
+		},
+		playlists: map[string][]*Track{
+			fakePlaylistID: catalog[:fakeCatalogSize/2],
+		},
+		device: Device{
+			ID:     fakeDeviceID,
+			Name:   "Fake Speaker",
+			Type:   "Speaker",
+			Active: true,
+			Volume: 50,
+		},
+	}
+}
+
+// NewFakeFactory returns a Factory creating a new Fake for every user
+func NewFakeFactory(c clock.Clock) Factory {
+	return func(ctx context.Context, token *oauth2.Token) MusicProvider {
+		return NewFake(c)
+	}
+}
+
+func (f *Fake) Search(_ context.Context, query string, searchType SearchType) (*SearchResult, error) {
+	f.Lock()
+	defer f.Unlock()
+	query = strings.ToLower(strings.TrimSuffix(query, "*"))
+
+	switch searchType {
+	case SearchTypeTrack:
+		page := &Page[Track]{Items: make([]Track, 0)}
+		for _, track := range f.catalog {
+			if strings.Contains(strings.ToLower(track.Name), query) || strings.Contains(strings.ToLower(track.Artists[0].Name), query) {
+				page.Items = append(page.Items, *track)
+			}
+		}
+		page.Total = len(page.Items)
+		return &SearchResult{Tracks: page}, nil
+	case SearchTypePlaylist:
+		page := &Page[Playlist]{Items: make([]Playlist, 0)}
+		for id := range f.playlists {
+			playlist := f.playlist(id)
+			if strings.Contains(strings.ToLower(playlist.Name), query) {
+				page.Items = append(page.Items, playlist)
+			}
+		}
+		page.Total = len(page.Items)
+		return &SearchResult{Playlists: page}, nil
+	case SearchTypeAlbum:
+		page := &Page[Album]{Items: make([]Album, 0)}
+		if strings.Contains(strings.ToLower(f.catalog[0].Album.Name), query) {
+			page.Items = append(page.Items, f.catalog[0].Album)
+		}
+		page.Total = len(page.Items)
+		return &SearchResult{Albums: page}, nil
+	default:
+		return nil, ErrSearchTypeInvalid
+	}
+}
+
+func (f *Fake) GetTrack(_ context.Context, trackID string) (*Track, error) {
+	f.Lock()
+	defer f.Unlock()
+	return f.track(trackID)
+}
+
+func (f *Fake) ArtistGenres(_ context.Context, artistIDs []string) (map[string][]string, error) {
+	f.Lock()
+	defer f.Unlock()
+	genres := make(map[string][]string, len(artistIDs))
+	for _, id := range artistIDs {
+		if artistGenres, ok := f.genres[id]; ok {
+			genres[id] = artistGenres
+		}
+	}
+	return genres, nil
+}
+
+func (f *Fake) PlaylistTracks(_ context.Context, playlistID string) ([]*Track, error) {
+	f.Lock()
+	defer f.Unlock()
+	tracks, ok := f.playlists[playlistID]
+	if !ok {
+		return nil, ErrPlaylistNotFound
+	}
+	return append([]*Track(nil), tracks...), nil
+}
+
+func (f *Fake) AlbumTracks(_ context.Context, albumID string) ([]*Track, error) {
+	f.Lock()
+	defer f.Unlock()
+	if albumID != fakeAlbumID {
+		return nil, ErrAlbumNotFound
+	}
+	return append([]*Track(nil), f.catalog...), nil
+}
+
+// Recommendations returns the tracks of the catalog following the first seed track
+func (f *Fake) Recommendations(_ context.Context, seedTrackIDs []string, limit int) ([]*Track, error) {
+	f.Lock()
+	defer f.Unlock()
+	start := 0
+	if len(seedTrackIDs) > 0 {
+		for i, track := range f.catalog {
+			if track.ID == seedTrackIDs[0] {
+				start = i + 1
+			}
+		}
+	}
+	tracks := make([]*Track, 0, limit)
+	for i := 0; i < len(f.catalog) && len(tracks) < limit; i++ {
+		tracks = append(tracks, f.catalog[(start+i)%len(f.catalog)])
+	}
+	return tracks, nil
+}
+
+func (f *Fake) Playlists(_ context.Context) (*Page[Playlist], error) {
+	f.Lock()
+	defer f.Unlock()
+	page := &Page[Playlist]{Items: make([]Playlist, 0, len(f.playlists))}
+	for id := range f.playlists {
+		page.Items = append(page.Items, f.playlist(id))
+	}
+	page.Total = len(page.Items)
+	return page, nil
+}
+
+func (f *Fake) CreatePlaylist(_ context.Context, name string, _ string, trackIDs []string) error {
+	f.Lock()
+	defer f.Unlock()
+	tracks := make([]*Track, 0, len(trackIDs))
+	for _, id := range trackIDs {
+		track, err := f.track(id)
+		if err != nil {
+			return err
+		}
+		tracks = append(tracks, track)
+	}
+	f.playlists[name] = tracks
+	return nil
+}
+
+func (f *Fake) Play(ctx context.Context, track *Track) error {
+	return f.PlayFrom(ctx, track, 0)
+}
+
+func (f *Fake) PlayFrom(_ context.Context, track *Track, progress int) error {
+	f.Lock()
+	defer f.Unlock()
+	if !f.device.Active {
+		return ErrDeviceNotFound
+	}
+	f.item = track
+	f.progress = progress
+	f.playing = true
+	f.startedAt = f.clock.Now().Add(-time.Duration(progress) * time.Millisecond)
+	return nil
+}
+
+// Enqueue sets the track played after the current one
+func (f *Fake) Enqueue(_ context.Context, track *Track) error {
+	f.Lock()
+	defer f.Unlock()
+	if !f.device.Active {
+		return ErrDeviceNotFound
+	}
+	f.next = track
+	return nil
+}
+
+func (f *Fake) Resume(_ context.Context) error {
+	f.Lock()
+	defer f.Unlock()
+	if f.item == nil || f.playing {
+		return nil
+	}
+	f.playing = true
+	f.startedAt = f.clock.Now().Add(-time.Duration(f.progress) * time.Millisecond)
+	return nil
+}
+
+func (f *Fake) Pause(_ context.Context) error {
+	f.Lock()
+	defer f.Unlock()
+	f.progress = f.currentProgress()
+	f.playing = false
+	return nil
+}
+
+func (f *Fake) SetVolume(_ context.Context, percent int) error {
+	f.Lock()
+	defer f.Unlock()
+	f.device.Volume = percent
+	return nil
+}
+
+func (f *Fake) Devices(_ context.Context) ([]Device, error) {
+	f.Lock()
+	defer f.Unlock()
+	return []Device{f.device}, nil
+}
+
+func (f *Fake) TransferPlayback(_ context.Context, deviceID string, play bool) error {
+	f.Lock()
+	defer f.Unlock()
+	if deviceID != f.device.ID {
+		return ErrDeviceNotFound
+	}
+	f.device.Active = true
+	if play && f.item != nil && !f.playing {
+		f.playing = true
+		f.startedAt = f.clock.Now().Add(-time.Duration(f.progress) * time.Millisecond)
+	}
+	return nil
+}
+
+// SetDeviceActive simulates the device going offline or coming back. An inactive device pauses the playback.
+func (f *Fake) SetDeviceActive(active bool) {
+	f.Lock()
+	defer f.Unlock()
+	if !active {
+		f.progress = f.currentProgress()
+		f.playing = false
+	}
+	f.device.Active = active
+}
+
+func (f *Fake) PlayerState(_ context.Context) (*PlayerState, error) {
+	f.Lock()
+	defer f.Unlock()
+	progress := f.currentProgress()
+	if f.playing && f.item != nil && progress >= f.item.Duration && f.next != nil {
+		// The enqueued track follows the ended one without a gap
+		f.startedAt = f.startedAt.Add(time.Duration(f.item.Duration) * time.Millisecond)
+		f.item = f.next
+		f.next = nil
+		progress = f.currentProgress()
+	}
+	if f.playing && f.item != nil && progress >= f.item.Duration {
+		// The track ended and there is nothing else to play
+		f.playing = false
+		progress = 0
+	}
+	f.progress = progress
+	return &PlayerState{
+		Device:    f.device,
+		Playing:   f.playing,
+		Progress:  f.progress,
+		Item:      f.item,
+		Timestamp: f.clock.Now().UnixMilli(),
+	}, nil
+}
+
+func (f *Fake) currentProgress() int {
+	if !f.playing {
+		return f.progress
+	}
+	return int(f.clock.Now().Sub(f.startedAt).Milliseconds())
+}
+
+func (f *Fake) track(trackID string) (*Track, error) {
+	for _, track := range f.catalog {
+		if track.ID == trackID {
+			return track, nil
+		}
+	}
+	return nil, ErrTrackNotFound
+}
+
+func (f *Fake) playlist(id string) Playlist {
+	name := id
+	if id == fakePlaylistID {
+		name = "Fake Playlist"
+	}
+	return Playlist{
+		ID:         id,
+		URI:        "fake:playlist:" + id,
+		Name:       name,
+		Owner:      "Fake User",
+		TrackCount: len(f.playlists[id]),
+	}
+}
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrTrackNotFound     = errors.New("track not found")
+	ErrPlaylistNotFound  = errors.New("playlist not found")
+	ErrAlbumNotFound     = errors.New("album not found")
+	ErrDeviceNotFound    = errors.New("device not found")
+	ErrSearchTypeInvalid = errors.New("invalid search type")
+)
+
+type SearchType string
+
+const (
+	SearchTypeTrack    SearchType = "track"
+	SearchTypePlaylist SearchType = "playlist"
+	SearchTypeAlbum    SearchType = "album"
+)
+
+// MusicProvider is the set of operations JamFactory needs from a music streaming service.
+// Every user holds its own MusicProvider, authorized with the user's token.
+type MusicProvider interface {
+	Search(ctx context.Context, query string, searchType SearchType) (*SearchResult, error)
+	GetTrack(ctx context.Context, trackID string) (*Track, error)
+	ArtistGenres(ctx context.Context, artistIDs []string) (map[string][]string, error)
+	PlaylistTracks(ctx context.Context, playlistID string) ([]*Track, error)
+	AlbumTracks(ctx context.Context, albumID string) ([]*Track, error)
+	Recommendations(ctx context.Context, seedTrackIDs []string, limit int) ([]*Track, error)
+	Playlists(ctx context.Context) (*Page[Playlist], error)
+	CreatePlaylist(ctx context.Context, name string, description string, trackIDs []string) error
+
+	Play(ctx context.Context, track *Track) error
+	// PlayFrom plays track starting at progress in milliseconds
+	PlayFrom(ctx context.Context, track *Track, progress int) error
+	Enqueue(ctx context.Context, track *Track) error
+	Resume(ctx context.Context) error
+	Pause(ctx context.Context) error
+	SetVolume(ctx context.Context, percent int) error
+	Devices(ctx context.Context) ([]Device, error)
+	TransferPlayback(ctx context.Context, deviceID string, play bool) error
+	PlayerState(ctx context.Context) (*PlayerState, error)
+}
+
+// Factory creates a MusicProvider for a user authorized with token
+type Factory func(ctx context.Context, token *oauth2.Token) MusicProvider
+
+// The provider-neutral model below mirrors the field names of the Spotify Web API,
+// so the JSON representation stays compatible with existing clients.
+
+type Image struct {
+	URL    string `json:"url"`
+	Height int    `json:"height"`
+	Width  int    `json:"width"`
+}
+
+type Artist struct {
+	ID   string `json:"id"`
+	URI  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type Album struct {
+	ID      string   `json:"id"`
+	URI     string   `json:"uri"`
+	Name    string   `json:"name"`
+	Artists []Artist `json:"artists"`
+	Images  []Image  `json:"images"`
+}
+
+type Track struct {
+	ID       string   `json:"id"`
+	URI      string   `json:"uri"`
+	Name     string   `json:"name"`
+	Artists  []Artist `json:"artists"`
+	Album    Album    `json:"album"`
+	Duration int      `json:"duration_ms"`
+	Explicit bool     `json:"explicit"`
+	ISRC     string   `json:"isrc,omitempty"`
+}
+
+// SameRecording reports whether t and other are the same recording. Tracks with different IDs are the same
+// recording, if they share their ISRC, e.g. a single and its album version.
+func (t *Track) SameRecording(other *Track) bool {
+	return t.ID == other.ID || (t.ISRC != "" && t.ISRC == other.ISRC)
+}
+
+type Playlist struct {
+	ID          string  `json:"id"`
+	URI         string  `json:"uri"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Owner       string  `json:"owner"`
+	Images      []Image `json:"images"`
+	TrackCount  int     `json:"track_count"`
+}
+
+type Device struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Active     bool   `json:"is_active"`
+	Restricted bool   `json:"is_restricted"`
+	Volume     int    `json:"volume_percent"`
+}
+
+type PlayerState struct {
+	Device    Device `json:"device"`
+	Playing   bool   `json:"is_playing"`
+	Progress  int    `json:"progress_ms"`
+	Item      *Track `json:"item"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+type Page[T any] struct {
+	Items []T `json:"items"`
+	Total int `json:"total"`
+}
+
+type SearchResult struct {
+	Artists   *Page[Artist]   `json:"artists"`
+	Albums    *Page[Album]    `json:"albums"`
+	Playlists *Page[Playlist] `json:"playlists"`
+	Tracks    *Page[Track]    `json:"tracks"`
+}
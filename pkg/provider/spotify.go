@@ -0,0 +1,363 @@
+package provider
+
+import (
+	"context"
+	"os"
+
+	"github.com/jamfactoryapp/jamfactory-backend/internal/utils"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/authenticator"
+	"github.com/zmb3/spotify/v2"
+	"golang.org/x/oauth2"
+)
+
+const (
+	playlistCoverFile  = "./assets/playlist_cover.png"
+	playlistChunkSize  = 100
+	albumTrackPageSize = 50
+	artistChunkSize    = 50
+)
+
+// Spotify is the MusicProvider backed by the Spotify Web API
+type Spotify struct {
+	client  *spotify.Client
+	country string
+}
+
+func NewSpotify(client *spotify.Client) *Spotify {
+	return &Spotify{
+		client:  client,
+		country: spotify.CountryGermany,
+	}
+}
+
+// NewSpotifyFactory returns a Factory creating Spotify clients using the authenticator
+func NewSpotifyFactory(auth *authenticator.Authenticator) Factory {
+	return func(ctx context.Context, token *oauth2.Token) MusicProvider {
+		return NewSpotify(spotify.New(auth.Client(ctx, token)))
+	}
+}
+
+func (s *Spotify) Search(ctx context.Context, query string, searchType SearchType) (*SearchResult, error) {
+	var spotifySearchType spotify.SearchType
+	switch searchType {
+	case SearchTypeTrack:
+		spotifySearchType = spotify.SearchTypeTrack
+	case SearchTypePlaylist:
+		spotifySearchType = spotify.SearchTypePlaylist
+	case SearchTypeAlbum:
+		spotifySearchType = spotify.SearchTypeAlbum
+	default:
+		return nil, ErrSearchTypeInvalid
+	}
+
+	result, err := s.client.Search(ctx, query, spotifySearchType, spotify.Country(s.country))
+	if err != nil {
+		return nil, err
+	}
+
+	searchResult := &SearchResult{}
+	if result.Artists != nil {
+		searchResult.Artists = &Page[Artist]{Items: make([]Artist, len(result.Artists.Artists)), Total: int(result.Artists.Total)}
+		for i := range result.Artists.Artists {
+			searchResult.Artists.Items[i] = convertArtist(result.Artists.Artists[i].SimpleArtist)
+		}
+	}
+	if result.Albums != nil {
+		searchResult.Albums = &Page[Album]{Items: make([]Album, len(result.Albums.Albums)), Total: int(result.Albums.Total)}
+		for i := range result.Albums.Albums {
+			searchResult.Albums.Items[i] = convertAlbum(result.Albums.Albums[i])
+		}
+	}
+	if result.Playlists != nil {
+		searchResult.Playlists = &Page[Playlist]{Items: make([]Playlist, len(result.Playlists.Playlists)), Total: int(result.Playlists.Total)}
+		for i := range result.Playlists.Playlists {
+			searchResult.Playlists.Items[i] = convertPlaylist(result.Playlists.Playlists[i])
+		}
+	}
+	if result.Tracks != nil {
+		searchResult.Tracks = &Page[Track]{Items: make([]Track, len(result.Tracks.Tracks)), Total: int(result.Tracks.Total)}
+		for i := range result.Tracks.Tracks {
+			searchResult.Tracks.Items[i] = *convertTrack(&result.Tracks.Tracks[i])
+		}
+	}
+	return searchResult, nil
+}
+
+func (s *Spotify) GetTrack(ctx context.Context, trackID string) (*Track, error) {
+	track, err := s.client.GetTrack(ctx, spotify.ID(trackID))
+	if err != nil {
+		return nil, err
+	}
+	return convertTrack(track), nil
+}
+
+// ArtistGenres returns the genres of the artists by artist ID
+func (s *Spotify) ArtistGenres(ctx context.Context, artistIDs []string) (map[string][]string, error) {
+	ids := make([]spotify.ID, len(artistIDs))
+	for i := range artistIDs {
+		ids[i] = spotify.ID(artistIDs[i])
+	}
+	genres := make(map[string][]string, len(artistIDs))
+	for _, chunk := range utils.SplitsIds(ids, artistChunkSize) {
+		artists, err := s.client.GetArtists(ctx, chunk...)
+		if err != nil {
+			return nil, err
+		}
+		for _, artist := range artists {
+			if artist != nil {
+				genres[artist.ID.String()] = artist.Genres
+			}
+		}
+	}
+	return genres, nil
+}
+
+func (s *Spotify) PlaylistTracks(ctx context.Context, playlistID string) ([]*Track, error) {
+	playlist, err := s.client.GetPlaylistItems(ctx, spotify.ID(playlistID))
+	if err != nil {
+		return nil, err
+	}
+	tracks := make([]*Track, 0, len(playlist.Items))
+	for _, item := range playlist.Items {
+		// Playlists can contain episodes or local files which can't be queued
+		if item.Track.Track == nil || item.Track.Track.ID == "" {
+			continue
+		}
+		tracks = append(tracks, convertTrack(item.Track.Track))
+	}
+	return tracks, nil
+}
+
+func (s *Spotify) AlbumTracks(ctx context.Context, albumID string) ([]*Track, error) {
+	album, err := s.client.GetAlbumTracks(ctx, spotify.ID(albumID), spotify.Limit(albumTrackPageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.fullTracks(ctx, album.Tracks)
+}
+
+// Recommendations returns up to limit tracks similar to the seed tracks. Spotify uses at most 5 seeds
+func (s *Spotify) Recommendations(ctx context.Context, seedTrackIDs []string, limit int) ([]*Track, error) {
+	seeds := spotify.Seeds{}
+	for _, id := range seedTrackIDs {
+		if len(seeds.Tracks) == spotify.MaxNumberOfSeeds {
+			break
+		}
+		seeds.Tracks = append(seeds.Tracks, spotify.ID(id))
+	}
+	recommendations, err := s.client.GetRecommendations(ctx, seeds, nil, spotify.Limit(limit), spotify.Country(s.country))
+	if err != nil {
+		return nil, err
+	}
+	return s.fullTracks(ctx, recommendations.Tracks)
+}
+
+// fullTracks fetches the full tracks of simple tracks, as simple tracks don't contain the album
+func (s *Spotify) fullTracks(ctx context.Context, simpleTracks []spotify.SimpleTrack) ([]*Track, error) {
+	if len(simpleTracks) == 0 {
+		return []*Track{}, nil
+	}
+	ids := make([]spotify.ID, len(simpleTracks))
+	for i := range simpleTracks {
+		ids[i] = simpleTracks[i].ID
+	}
+	fullTracks, err := s.client.GetTracks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]*Track, 0, len(fullTracks))
+	for _, track := range fullTracks {
+		if track == nil {
+			continue
+		}
+		tracks = append(tracks, convertTrack(track))
+	}
+	return tracks, nil
+}
+
+func (s *Spotify) Playlists(ctx context.Context) (*Page[Playlist], error) {
+	playlists, err := s.client.CurrentUsersPlaylists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page[Playlist]{
+		Items: make([]Playlist, len(playlists.Playlists)),
+		Total: int(playlists.Total),
+	}
+	for i := range playlists.Playlists {
+		page.Items[i] = convertPlaylist(playlists.Playlists[i])
+	}
+	return page, nil
+}
+
+func (s *Spotify) CreatePlaylist(ctx context.Context, name string, description string, trackIDs []string) error {
+	user, err := s.client.CurrentUser(ctx)
+	if err != nil {
+		return err
+	}
+	playlist, err := s.client.CreatePlaylistForUser(ctx, user.ID, name, description, false, false)
+	if err != nil {
+		return err
+	}
+	if utils.FileExists(playlistCoverFile) {
+		file, err := os.Open(playlistCoverFile)
+		if err != nil {
+			return err
+		}
+		defer utils.CloseProperly(file)
+		err = s.client.SetPlaylistImage(ctx, playlist.ID, file)
+		if err != nil {
+			return err
+		}
+	}
+
+	ids := make([]spotify.ID, len(trackIDs))
+	for i := range trackIDs {
+		ids[i] = spotify.ID(trackIDs[i])
+	}
+	idChunks := utils.SplitsIds(ids, playlistChunkSize)
+	for i := range idChunks {
+		_, err := s.client.AddTracksToPlaylist(ctx, playlist.ID, idChunks[i]...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Spotify) Play(ctx context.Context, track *Track) error {
+	return s.PlayFrom(ctx, track, 0)
+}
+
+func (s *Spotify) PlayFrom(ctx context.Context, track *Track, progress int) error {
+	return s.client.PlayOpt(ctx, &spotify.PlayOptions{
+		URIs:       []spotify.URI{spotify.URI(track.URI)},
+		PositionMs: progress,
+	})
+}
+
+// Enqueue adds track to the queue of the device, so it follows the current track without a gap
+func (s *Spotify) Enqueue(ctx context.Context, track *Track) error {
+	return s.client.QueueSong(ctx, spotify.ID(track.ID))
+}
+
+func (s *Spotify) Resume(ctx context.Context) error {
+	return s.client.Play(ctx)
+}
+
+func (s *Spotify) Pause(ctx context.Context) error {
+	return s.client.Pause(ctx)
+}
+
+func (s *Spotify) SetVolume(ctx context.Context, percent int) error {
+	return s.client.Volume(ctx, percent)
+}
+
+func (s *Spotify) Devices(ctx context.Context) ([]Device, error) {
+	devices, err := s.client.PlayerDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	converted := make([]Device, len(devices))
+	for i := range devices {
+		converted[i] = convertDevice(devices[i])
+	}
+	return converted, nil
+}
+
+func (s *Spotify) TransferPlayback(ctx context.Context, deviceID string, play bool) error {
+	return s.client.TransferPlayback(ctx, spotify.ID(deviceID), play)
+}
+
+func (s *Spotify) PlayerState(ctx context.Context) (*PlayerState, error) {
+	state, err := s.client.PlayerState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	playerState := &PlayerState{
+		Device:    convertDevice(state.Device),
+		Playing:   state.Playing,
+		Progress:  state.Progress,
+		Timestamp: state.Timestamp,
+	}
+	if state.Item != nil {
+		playerState.Item = convertTrack(state.Item)
+	}
+	return playerState, nil
+}
+
+func convertTrack(track *spotify.FullTrack) *Track {
+	artists := make([]Artist, len(track.Artists))
+	for i := range track.Artists {
+		artists[i] = convertArtist(track.Artists[i])
+	}
+	return &Track{
+		ID:       track.ID.String(),
+		URI:      string(track.URI),
+		Name:     track.Name,
+		Artists:  artists,
+		Album:    convertAlbum(track.Album),
+		Duration: track.Duration,
+		Explicit: track.Explicit,
+		ISRC:     track.ExternalIDs["isrc"],
+	}
+}
+
+func convertArtist(artist spotify.SimpleArtist) Artist {
+	return Artist{
+		ID:   artist.ID.String(),
+		URI:  string(artist.URI),
+		Name: artist.Name,
+	}
+}
+
+func convertAlbum(album spotify.SimpleAlbum) Album {
+	artists := make([]Artist, len(album.Artists))
+	for i := range album.Artists {
+		artists[i] = convertArtist(album.Artists[i])
+	}
+	return Album{
+		ID:      album.ID.String(),
+		URI:     string(album.URI),
+		Name:    album.Name,
+		Artists: artists,
+		Images:  convertImages(album.Images),
+	}
+}
+
+func convertPlaylist(playlist spotify.SimplePlaylist) Playlist {
+	return Playlist{
+		ID:          playlist.ID.String(),
+		URI:         string(playlist.URI),
+		Name:        playlist.Name,
+		Description: playlist.Description,
+		Owner:       playlist.Owner.DisplayName,
+		Images:      convertImages(playlist.Images),
+		TrackCount:  int(playlist.Tracks.Total),
+	}
+}
+
+func convertDevice(device spotify.PlayerDevice) Device {
+	return Device{
+		ID:         device.ID.String(),
+		Name:       device.Name,
+		Type:       device.Type,
+		Active:     device.Active,
+		Restricted: device.Restricted,
+		Volume:     device.Volume,
+	}
+}
+
+func convertImages(images []spotify.Image) []Image {
+	converted := make([]Image, len(images))
+	for i := range images {
+		converted[i] = Image{
+			URL:    images[i].URL,
+			Height: int(images[i].Height),
+			Width:  int(images[i].Width),
+		}
+	}
+	return converted
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+)
+
+// FallbackPreview is the number of upcoming fallback songs listed in queue responses
+const FallbackPreview = 5
+
+// UpcomingFallback returns the next songs of the fallback source, which are played once the queue runs dry.
+// Queued songs always come first, so no fallback songs are returned while songs are queued.
+func (q *Queue) UpcomingFallback() []*provider.Track {
+	if len(q.Songs) > 0 || len(q.Fallback) == 0 {
+		return nil
+	}
+	if len(q.Fallback) > FallbackPreview {
+		return q.Fallback[:FallbackPreview]
+	}
+	return q.Fallback
+}
+
+// PeekFallback returns the next fallback song without removing it from the queue. Songs played too recently for the
+// Replay rule are dropped. nil is returned, if no fallback songs are left.
+func (q *Queue) PeekFallback(replay Replay, now time.Time) *provider.Track {
+	for len(q.Fallback) > 0 {
+		track := q.Fallback[0]
+		if !q.RecentlyPlayed(track, replay, now) {
+			return track
+		}
+		q.Fallback = q.Fallback[1:]
+	}
+	return nil
+}
+
+// RemoveFallback removes the fallback song with the trackID from the queue
+func (q *Queue) RemoveFallback(trackID string) {
+	for i, track := range q.Fallback {
+		if track.ID == trackID {
+			q.Fallback = append(q.Fallback[:i], q.Fallback[i+1:]...)
+			return
+		}
+	}
+}
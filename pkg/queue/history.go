@@ -0,0 +1,87 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+)
+
+const (
+	// HistoryRetention is the number of played songs kept in the history. Older songs are dropped
+	HistoryRetention = 500
+	// skipTolerance is the time a song may end early, without being counted as skipped
+	skipTolerance = 5 * time.Second
+)
+
+// PlayedSong is a song in the history of a queue. Fallback is set for songs of the fallback source
+type PlayedSong struct {
+	ID        string
+	Track     *provider.Track
+	Submitter string
+	Votes     int
+	AddedAt   time.Time
+	StartedAt time.Time
+	Skipped   bool
+	Fallback  bool
+}
+
+// Advance records track in the history as started at startedAt. If the track is queued, its submitter and votes
+// are recorded, but it stays in the queue. The previous song is marked as skipped, if it was replaced before its end.
+// Votes to skip the previous song are discarded. The recorded song is returned.
+func (q *Queue) Advance(track *provider.Track, startedAt time.Time) *PlayedSong {
+	q.Skip = SkipVote{}
+
+	if len(q.History) > 0 {
+		previous := q.History[len(q.History)-1]
+		end := previous.StartedAt.Add(time.Duration(previous.Track.Duration)*time.Millisecond - skipTolerance)
+		if startedAt.Before(end) {
+			previous.Skipped = true
+		}
+	}
+
+	played := &PlayedSong{
+		ID:        track.ID,
+		Track:     track,
+		AddedAt:   startedAt,
+		StartedAt: startedAt,
+	}
+	if q.containsSong(track.ID) {
+		so := q.Songs[q.indexOf(track.ID)]
+		played.Submitter = so.Submitter
+		played.Votes = len(so.GetVotes())
+		played.AddedAt = so.Date
+	}
+	q.History = append(q.History, played)
+
+	if len(q.History) > HistoryRetention {
+		q.History = append(q.History[:0], q.History[len(q.History)-HistoryRetention:]...)
+	}
+	return played
+}
+
+// PlayingFallback reports whether the song trackID, which is currently playing, is a song of the fallback source
+func (q *Queue) PlayingFallback(trackID string) bool {
+	if len(q.History) == 0 {
+		return false
+	}
+	last := q.History[len(q.History)-1]
+	return last.Fallback && last.ID == trackID
+}
+
+// GetHistory returns up to limit played songs starting at offset, the most recently played song first
+func (q *Queue) GetHistory(offset int, limit int) []types.PlayedSong {
+	songs := make([]types.PlayedSong, 0)
+	for i := len(q.History) - 1 - offset; i >= 0 && len(songs) < limit; i-- {
+		s := q.History[i]
+		songs = append(songs, types.PlayedSong{
+			Song:      s.Track,
+			Votes:     s.Votes,
+			AddedBy:   s.Submitter,
+			StartedAt: s.StartedAt,
+			Skipped:   s.Skipped,
+			Fallback:  s.Fallback,
+		})
+	}
+	return songs
+}
@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"sort"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/song"
+)
+
+// Ordering is the strategy deciding in which order the songs of a queue are played
+type Ordering string
+
+const (
+	// OrderingVotes plays the songs with the highest score first
+	OrderingVotes Ordering = "votes"
+	// OrderingRoundRobin takes turns between the members who submitted songs
+	OrderingRoundRobin Ordering = "round_robin"
+	// OrderingFairShare weights the positive score of a song down by the number of songs already played for its
+	// submitter
+	OrderingFairShare Ordering = "fair_share"
+	// OrderingFIFO plays the songs in the order they were submitted
+	OrderingFIFO Ordering = "fifo"
+)
+
+// Effective returns the ordering used by Sort. JamSessions created before orderings existed sort by votes
+func (o Ordering) Effective() Ordering {
+	if o == "" {
+		return OrderingVotes
+	}
+	return o
+}
+
+func (o Ordering) Valid() bool {
+	switch o {
+	case OrderingVotes, OrderingRoundRobin, OrderingFairShare, OrderingFIFO:
+		return true
+	default:
+		return false
+	}
+}
+
+// Sort orders the songs of the queue using ordering.
+// Songs added from collections by the host are background music. They are played after the songs
+// submitted by members, except with OrderingVotes, where they are only ranked last among songs with equal votes.
+func (q *Queue) Sort(ordering Ordering) {
+	switch ordering.Effective() {
+	case OrderingRoundRobin:
+		q.sortRoundRobin()
+	case OrderingFairShare:
+		q.sortFairShare()
+	case OrderingFIFO:
+		q.sortFIFO()
+	default:
+		q.sortVotes()
+	}
+}
+
+func (q *Queue) sortVotes() {
+	sort.SliceStable(q.Songs, func(i, j int) bool {
+		a, b := q.Songs[i], q.Songs[j]
+		if a.Score() != b.Score() {
+			return a.Score() > b.Score()
+		}
+		if isCollectionSong(a) != isCollectionSong(b) {
+			return isCollectionSong(b)
+		}
+		return a.Date.Before(b.Date)
+	})
+}
+
+func (q *Queue) sortFIFO() {
+	sort.SliceStable(q.Songs, func(i, j int) bool {
+		a, b := q.Songs[i], q.Songs[j]
+		if isCollectionSong(a) != isCollectionSong(b) {
+			return isCollectionSong(b)
+		}
+		return a.Date.Before(b.Date)
+	})
+}
+
+func (q *Queue) sortFairShare() {
+	played := q.playedPerSubmitter()
+	// Only positive scores are weighted down, dividing a negative score would rank the song higher
+	share := func(s *song.Song) float64 {
+		if s.Score() <= 0 {
+			return float64(s.Score())
+		}
+		return float64(s.Score()) / float64(1+played[s.Submitter])
+	}
+	sort.SliceStable(q.Songs, func(i, j int) bool {
+		a, b := q.Songs[i], q.Songs[j]
+		if isCollectionSong(a) != isCollectionSong(b) {
+			return isCollectionSong(b)
+		}
+		if share(a) != share(b) {
+			return share(a) > share(b)
+		}
+		return a.Date.Before(b.Date)
+	})
+}
+
+// sortRoundRobin gives every submitter a turn, starting with the submitter who waited the longest.
+// The songs of a submitter are played by votes and submission date.
+func (q *Queue) sortRoundRobin() {
+	q.sortVotes()
+
+	lastPlayed := q.lastPlayedPerSubmitter()
+	turns := make(map[string][]*song.Song)
+	submitters := make([]string, 0)
+	collection := make([]*song.Song, 0)
+	for _, s := range q.Songs {
+		if isCollectionSong(s) {
+			collection = append(collection, s)
+			continue
+		}
+		if _, ok := turns[s.Submitter]; !ok {
+			submitters = append(submitters, s.Submitter)
+		}
+		turns[s.Submitter] = append(turns[s.Submitter], s)
+	}
+	// Submitters who never played come first, the others in the order they played last
+	sort.SliceStable(submitters, func(i, j int) bool {
+		return lastPlayed[submitters[i]] < lastPlayed[submitters[j]]
+	})
+
+	songs := make([]*song.Song, 0, len(q.Songs))
+	for round := 0; len(songs) < len(q.Songs)-len(collection); round++ {
+		for _, submitter := range submitters {
+			if round < len(turns[submitter]) {
+				songs = append(songs, turns[submitter][round])
+			}
+		}
+	}
+	q.Songs = append(songs, collection...)
+}
+
+// playedPerSubmitter counts the songs in the history for each submitter
+func (q *Queue) playedPerSubmitter() map[string]int {
+	played := make(map[string]int)
+	for _, s := range q.History {
+		played[s.Submitter]++
+	}
+	return played
+}
+
+// lastPlayedPerSubmitter returns the position of the last song in the history for each submitter.
+// Positions start at 1, so submitters without played songs map to 0.
+func (q *Queue) lastPlayedPerSubmitter() map[string]int {
+	lastPlayed := make(map[string]int)
+	for i, s := range q.History {
+		lastPlayed[s.Submitter] = i + 1
+	}
+	return lastPlayed
+}
+
+func isCollectionSong(s *song.Song) bool {
+	return s.Submitter == HostVoteIdentifier
+}
@@ -0,0 +1,191 @@
+package queue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/song"
+)
+
+var epoch = time.Date(2021, 6, 1, 20, 0, 0, 0, time.UTC)
+
+// newSong returns a song submitted by submitter minute minutes after epoch with votes upvotes and downvotes
+// downvotes
+func newSong(id string, submitter string, minute int, votes int, downvotes int) *song.Song {
+	s := song.New(&provider.Track{ID: id, Duration: 180000}, submitter)
+	s.Date = epoch.Add(time.Duration(minute) * time.Minute)
+	for i := 0; i < votes; i++ {
+		s.Votes[id+"-voter-"+string(rune('a'+i))] = true
+	}
+	for i := 0; i < downvotes; i++ {
+		s.Downvotes[id+"-downvoter-"+string(rune('a'+i))] = true
+	}
+	return s
+}
+
+// played returns a history of songs played in order for submitters
+func played(submitters ...string) []*PlayedSong {
+	history := make([]*PlayedSong, len(submitters))
+	for i, submitter := range submitters {
+		history[i] = &PlayedSong{ID: "played", Submitter: submitter}
+	}
+	return history
+}
+
+func ids(q *Queue) []string {
+	ids := make([]string, len(q.Songs))
+	for i, s := range q.Songs {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func TestSort(t *testing.T) {
+	tests := []struct {
+		name     string
+		ordering Ordering
+		songs    []*song.Song
+		history  []*PlayedSong
+		want     []string
+	}{
+		{
+			name:     "votes by score",
+			ordering: OrderingVotes,
+			songs: []*song.Song{
+				newSong("a", "alice", 0, 1, 0),
+				newSong("b", "bob", 1, 3, 0),
+				newSong("c", "carol", 2, 2, 1),
+			},
+			want: []string{"b", "a", "c"},
+		},
+		{
+			name:     "votes by date for equal scores",
+			ordering: OrderingVotes,
+			songs: []*song.Song{
+				newSong("a", "alice", 2, 1, 0),
+				newSong("b", "bob", 1, 1, 0),
+			},
+			want: []string{"b", "a"},
+		},
+		{
+			name:     "votes rank collection songs last among equal scores",
+			ordering: OrderingVotes,
+			songs: []*song.Song{
+				newSong("collection", HostVoteIdentifier, 0, 1, 0),
+				newSong("a", "alice", 1, 1, 0),
+				newSong("b", "bob", 2, 2, 0),
+			},
+			want: []string{"b", "a", "collection"},
+		},
+		{
+			name:     "empty ordering sorts by votes",
+			ordering: "",
+			songs: []*song.Song{
+				newSong("a", "alice", 0, 1, 0),
+				newSong("b", "bob", 1, 2, 0),
+			},
+			want: []string{"b", "a"},
+		},
+		{
+			name:     "fifo by date",
+			ordering: OrderingFIFO,
+			songs: []*song.Song{
+				newSong("b", "bob", 1, 5, 0),
+				newSong("a", "alice", 0, 1, 0),
+				newSong("c", "carol", 2, 0, 2),
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name:     "fifo plays collection songs last",
+			ordering: OrderingFIFO,
+			songs: []*song.Song{
+				newSong("collection", HostVoteIdentifier, 0, 1, 0),
+				newSong("a", "alice", 1, 1, 0),
+			},
+			want: []string{"a", "collection"},
+		},
+		{
+			name:     "round robin takes turns between submitters",
+			ordering: OrderingRoundRobin,
+			songs: []*song.Song{
+				newSong("a1", "alice", 0, 3, 0),
+				newSong("a2", "alice", 1, 2, 0),
+				newSong("a3", "alice", 2, 1, 0),
+				newSong("b1", "bob", 3, 1, 0),
+				newSong("c1", "carol", 4, 1, 0),
+			},
+			want: []string{"a1", "b1", "c1", "a2", "a3"},
+		},
+		{
+			name:     "round robin starts with the submitter who waited the longest",
+			ordering: OrderingRoundRobin,
+			songs: []*song.Song{
+				newSong("a1", "alice", 0, 2, 0),
+				newSong("b1", "bob", 1, 1, 0),
+				newSong("c1", "carol", 2, 1, 0),
+			},
+			history: played("bob", "alice"),
+			want:    []string{"c1", "b1", "a1"},
+		},
+		{
+			name:     "round robin plays collection songs last",
+			ordering: OrderingRoundRobin,
+			songs: []*song.Song{
+				newSong("collection", HostVoteIdentifier, 0, 5, 0),
+				newSong("a1", "alice", 1, 1, 0),
+			},
+			want: []string{"a1", "collection"},
+		},
+		{
+			name:     "fair share weights scores by played songs",
+			ordering: OrderingFairShare,
+			songs: []*song.Song{
+				newSong("a", "alice", 0, 3, 0),
+				newSong("b", "bob", 1, 2, 0),
+			},
+			history: played("alice", "alice"),
+			want:    []string{"b", "a"},
+		},
+		{
+			name:     "fair share doesn't favour negative scores of heavy submitters",
+			ordering: OrderingFairShare,
+			songs: []*song.Song{
+				newSong("a", "alice", 0, 0, 2),
+				newSong("b", "bob", 1, 0, 1),
+			},
+			history: played("alice", "alice"),
+			want:    []string{"b", "a"},
+		},
+		{
+			name:     "fair share by date for equal shares",
+			ordering: OrderingFairShare,
+			songs: []*song.Song{
+				newSong("a", "alice", 1, 2, 0),
+				newSong("b", "bob", 0, 1, 0),
+			},
+			history: played("alice"),
+			want:    []string{"b", "a"},
+		},
+		{
+			name:     "fair share plays collection songs last",
+			ordering: OrderingFairShare,
+			songs: []*song.Song{
+				newSong("collection", HostVoteIdentifier, 0, 5, 0),
+				newSong("a", "alice", 1, 0, 1),
+			},
+			want: []string{"a", "collection"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &Queue{Songs: tt.songs, History: tt.history}
+			q.Sort(tt.ordering)
+			if got := ids(q); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Sort(%q) = %v, want %v", tt.ordering, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,12 +2,10 @@ package queue
 
 import (
 	"errors"
-	"sort"
-	"time"
 
 	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/song"
-	"github.com/zmb3/spotify/v2"
 )
 
 var (
@@ -19,9 +17,14 @@ const (
 	HostVoteIdentifier string = "Host"
 )
 
+// Queue of a JamSession. Fallback contains the songs of the fallback source, which are played, when no songs are
+// queued.
 type Queue struct {
-	Songs   []*song.Song
-	History []*song.Song
+	Songs     []*song.Song
+	History   []*PlayedSong
+	Skip      SkipVote
+	Additions []Addition
+	Fallback  []*provider.Track
 }
 
 func New() *Queue {
@@ -30,28 +33,15 @@ func New() *Queue {
 	}
 }
 
-func (q *Queue) Len() int {
-	return len(q.Songs)
-}
-
-func (q *Queue) Less(i, j int) bool {
-	if len(q.Songs[i].GetVotes()) != len(q.Songs[j].GetVotes()) {
-		return len(q.Songs[i].GetVotes()) > len(q.Songs[j].GetVotes())
-	}
-	return q.Songs[i].Date.Before(q.Songs[j].Date)
-}
-
-func (q *Queue) Swap(i, j int) {
-	q.Songs[i], q.Songs[j] = q.Songs[j], q.Songs[i]
-}
-
 func (q *Queue) Tracks() []types.Song {
 	songs := make([]types.Song, len(q.Songs))
 	for i, s := range q.Songs {
 		songs[i] = types.Song{
-			Song:  s.Track,
-			Votes: len(s.GetVotes()),
-			Voted: false,
+			Song:      s.Track,
+			Votes:     len(s.GetVotes()),
+			Voted:     false,
+			Downvotes: len(s.GetDownvotes()),
+			Downvoted: false,
 		}
 	}
 	return songs
@@ -61,9 +51,11 @@ func (q *Queue) For(voteID string) []types.Song {
 	songs := make([]types.Song, 0)
 	for _, s := range q.Songs {
 		songs = append(songs, types.Song{
-			Song:  s.Track,
-			Votes: len(s.GetVotes()),
-			Voted: s.HasVote(voteID),
+			Song:      s.Track,
+			Votes:     len(s.GetVotes()),
+			Voted:     s.HasVote(voteID),
+			Downvotes: len(s.GetDownvotes()),
+			Downvoted: s.HasDownvote(voteID),
 		})
 	}
 	return songs
@@ -78,47 +70,73 @@ func (q *Queue) GetNext() (*song.Song, error) {
 	return s, nil
 }
 
-func (q *Queue) GetHistory(voteID string) []types.Song {
-	songs := make([]types.Song, 0)
-	for _, s := range q.History {
-		songs = append(songs, types.Song{
-			Song:  s.Track,
-			Votes: len(s.GetVotes()),
-			Voted: s.HasVote(voteID),
-		})
+// Vote flips the vote of voteID for the song. Another release of a queued recording counts as the queued song.
+// If the song isn't queued yet, it is added with voteID as submitter and the addition is recorded for the Quota.
+// The queue needs to be sorted afterwards.
+func (q *Queue) Vote(songID string, voteID string, track *provider.Track) error {
+	index := q.indexOf(songID)
+	if index < 0 && track != nil {
+		index = q.indexOfRecording(track)
 	}
-	return songs
-}
-
-func (q *Queue) Vote(songID string, voteID string, s interface{}) error {
-	if q.containsSong(songID) {
-		so := q.Songs[q.indexOf(songID)]
-		so.Vote(voteID)
+	if index >= 0 {
+		q.Songs[index].Vote(voteID)
 	} else {
-		so, err := q.add(s)
+		so, err := q.add(track, voteID)
 		if err != nil {
 			return err
 		}
-		if voteID == HostVoteIdentifier {
-			so.Date = so.Date.Add(time.Hour * 24 * 365)
-		}
 		so.Vote(voteID)
+		q.recordAddition(voteID, so.Date)
 	}
 
 	q.removeEmptySongs()
-	sort.Sort(q)
 	return nil
 }
 
-func (q *Queue) Advance() error {
-	if len(q.Songs) == 0 {
-		return ErrQueueEmpty
+// Upvote adds the vote of voteID for the song, unless voteID already voted for it or another release of its
+// recording. The queue needs to be sorted afterwards.
+func (q *Queue) Upvote(track *provider.Track, voteID string) error {
+	if index := q.indexOfRecording(track); index >= 0 && q.Songs[index].HasVote(voteID) {
+		return nil
 	}
-	q.History = append(q.History, q.Songs[0])
-	q.Songs = append(q.Songs[:0], q.Songs[1:]...)
+	return q.Vote(track.ID, voteID, track)
+}
+
+// Downvote flips the downvote of voteID for a queued song. The queue needs to be sorted afterwards.
+func (q *Queue) Downvote(songID string, voteID string) error {
+	if !q.containsSong(songID) {
+		return ErrSongNotFound
+	}
+	q.Songs[q.indexOf(songID)].Downvote(voteID)
+	q.removeEmptySongs()
 	return nil
 }
 
+// RemoveDownvoted removes all songs with a score of -threshold or less. A threshold of 0 keeps all songs
+func (q *Queue) RemoveDownvoted(threshold int) {
+	if threshold <= 0 {
+		return
+	}
+	songs := q.Songs[:0]
+	for _, s := range q.Songs {
+		if s.Score() > -threshold {
+			songs = append(songs, s)
+		}
+	}
+	q.Songs = songs
+}
+
+// RemoveVotes removes all votes, downvotes and the skip vote of voteID. Songs without votes left are removed.
+// The queue needs to be sorted afterwards.
+func (q *Queue) RemoveVotes(voteID string) {
+	for _, s := range q.Songs {
+		delete(s.Votes, voteID)
+		delete(s.Downvotes, voteID)
+	}
+	delete(q.Skip.Voters, voteID)
+	q.removeEmptySongs()
+}
+
 func (q *Queue) Delete(songID string) {
 	if !q.containsSong(songID) {
 		return
@@ -128,12 +146,22 @@ func (q *Queue) Delete(songID string) {
 	return
 }
 
-func (q *Queue) add(s interface{}) (*song.Song, error) {
-	so := song.New(s.(*spotify.FullTrack))
+func (q *Queue) add(track *provider.Track, submitter string) (*song.Song, error) {
+	so := song.New(track, submitter)
 	q.Songs = append(q.Songs, so)
 	return so, nil
 }
 
+// Contains reports whether the song is queued
+func (q *Queue) Contains(songID string) bool {
+	return q.containsSong(songID)
+}
+
+// ContainsRecording reports whether the song or another release of its recording is queued
+func (q *Queue) ContainsRecording(track *provider.Track) bool {
+	return q.indexOfRecording(track) >= 0
+}
+
 func (q *Queue) containsSong(songID string) bool {
 	for _, s := range q.Songs {
 		if s.ID == songID {
@@ -152,10 +180,21 @@ func (q *Queue) indexOf(songID string) int {
 	return -1
 }
 
+func (q *Queue) indexOfRecording(track *provider.Track) int {
+	for i, s := range q.Songs {
+		if s.Track != nil && s.Track.SameRecording(track) {
+			return i
+		}
+	}
+	return -1
+}
+
 func (q *Queue) removeEmptySongs() {
+	songs := q.Songs[:0]
 	for _, s := range q.Songs {
-		if len(s.GetVotes()) == 0 {
-			q.Delete(s.ID)
+		if len(s.GetVotes()) > 0 {
+			songs = append(songs, s)
 		}
 	}
+	q.Songs = songs
 }
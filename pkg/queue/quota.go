@@ -0,0 +1,148 @@
+package queue
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/api/types"
+)
+
+// AdditionRetention is the time additions are remembered for. Quota windows and cooldowns can't be longer
+const AdditionRetention = time.Hour
+
+var (
+	ErrPendingLimit  = errors.New("too many songs in the queue")
+	ErrAdditionLimit = errors.New("too many songs added recently")
+	ErrCooldown      = errors.New("song added too recently")
+)
+
+// Quota limits the songs a member can add to the queue. MaxPending limits the queued songs submitted by the member,
+// MaxAdditions the songs added within Window and Cooldown is the minimum time between two additions.
+// Zero values disable the limits.
+type Quota struct {
+	MaxPending   int
+	MaxAdditions int
+	Window       time.Duration
+	Cooldown     time.Duration
+}
+
+// QuotaError is returned, if a member exceeded the Quota. RetryAfter is zero, if the member has to wait until
+// queued songs were played or removed.
+type QuotaError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *QuotaError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QuotaError) Unwrap() error {
+	return e.Err
+}
+
+// Addition records that Submitter added a song to the queue
+type Addition struct {
+	Submitter string
+	At        time.Time
+}
+
+// CheckQuota returns a *QuotaError, if submitter can't add another song to the queue at now
+func (q *Queue) CheckQuota(submitter string, quota Quota, now time.Time) error {
+	if quota.MaxPending > 0 && q.pending(submitter) >= quota.MaxPending {
+		return &QuotaError{Err: ErrPendingLimit}
+	}
+	if retryAfter := q.additionRetry(submitter, quota, now); retryAfter > 0 {
+		return &QuotaError{Err: ErrAdditionLimit, RetryAfter: retryAfter}
+	}
+	if retryAfter := q.cooldownRetry(submitter, quota, now); retryAfter > 0 {
+		return &QuotaError{Err: ErrCooldown, RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// RemainingQuota returns how many songs submitter can still add to the queue at now
+func (q *Queue) RemainingQuota(submitter string, quota Quota, now time.Time) *types.QueueQuota {
+	remaining := &types.QueueQuota{
+		Pending:   -1,
+		Additions: -1,
+	}
+	if quota.MaxPending > 0 {
+		remaining.Pending = nonNegative(quota.MaxPending - q.pending(submitter))
+	}
+	if quota.MaxAdditions > 0 && quota.Window > 0 {
+		remaining.Additions = nonNegative(quota.MaxAdditions - len(q.additionsSince(submitter, now.Add(-quota.Window))))
+	}
+	retryAfter := q.additionRetry(submitter, quota, now)
+	if cooldown := q.cooldownRetry(submitter, quota, now); cooldown > retryAfter {
+		retryAfter = cooldown
+	}
+	remaining.RetryAfter = int(retryAfter.Round(time.Second).Seconds())
+	return remaining
+}
+
+// pending returns the number of queued songs submitted by submitter
+func (q *Queue) pending(submitter string) int {
+	pending := 0
+	for _, s := range q.Songs {
+		if s.Submitter == submitter {
+			pending++
+		}
+	}
+	return pending
+}
+
+// additionRetry returns the time until submitter is below MaxAdditions within Window again
+func (q *Queue) additionRetry(submitter string, quota Quota, now time.Time) time.Duration {
+	if quota.MaxAdditions <= 0 || quota.Window <= 0 {
+		return 0
+	}
+	additions := q.additionsSince(submitter, now.Add(-quota.Window))
+	if len(additions) < quota.MaxAdditions {
+		return 0
+	}
+	// Wait until enough additions left the window
+	return additions[len(additions)-quota.MaxAdditions].At.Add(quota.Window).Sub(now)
+}
+
+// cooldownRetry returns the time until the Cooldown after the last addition of submitter ended
+func (q *Queue) cooldownRetry(submitter string, quota Quota, now time.Time) time.Duration {
+	if quota.Cooldown <= 0 {
+		return 0
+	}
+	additions := q.additionsSince(submitter, now.Add(-quota.Cooldown))
+	if len(additions) == 0 {
+		return 0
+	}
+	return additions[len(additions)-1].At.Add(quota.Cooldown).Sub(now)
+}
+
+// additionsSince returns the additions of submitter after since, the oldest first
+func (q *Queue) additionsSince(submitter string, since time.Time) []Addition {
+	var additions []Addition
+	for _, addition := range q.Additions {
+		if addition.Submitter == submitter && addition.At.After(since) {
+			additions = append(additions, addition)
+		}
+	}
+	return additions
+}
+
+// recordAddition remembers the addition of a song by submitter and forgets additions older than AdditionRetention
+func (q *Queue) recordAddition(submitter string, at time.Time) {
+	additions := q.Additions[:0]
+	for _, addition := range q.Additions {
+		if at.Sub(addition.At) < AdditionRetention {
+			additions = append(additions, addition)
+		}
+	}
+	q.Additions = append(additions, Addition{Submitter: submitter, At: at})
+}
+
+// nonNegative returns n, or 0 if n is negative
+func nonNegative(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
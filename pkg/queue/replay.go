@@ -0,0 +1,40 @@
+package queue
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+)
+
+// MaxReplayCooldown is the longest time a Replay rule can keep songs from being queued again
+const MaxReplayCooldown = 24 * time.Hour
+
+var (
+	ErrRecentlyPlayed = errors.New("song was played recently")
+)
+
+// Replay keeps songs from being queued again right after they were played. A song can't be added within Cooldown
+// after it started playing, or before Songs other songs were played after it. Zero values disable the limits.
+// Different releases of the same recording count as the same song.
+type Replay struct {
+	Cooldown time.Duration
+	Songs    int
+}
+
+// RecentlyPlayed reports whether the recording of track was played too recently to be queued again at now
+func (q *Queue) RecentlyPlayed(track *provider.Track, replay Replay, now time.Time) bool {
+	for i := len(q.History) - 1; i >= 0; i-- {
+		played := q.History[i]
+		withinSongs := replay.Songs > 0 && len(q.History)-1-i < replay.Songs
+		withinCooldown := replay.Cooldown > 0 && now.Sub(played.StartedAt) < replay.Cooldown
+		if !withinSongs && !withinCooldown {
+			// The history is ordered, so all earlier songs are outside the limits as well
+			return false
+		}
+		if played.Track != nil && played.Track.SameRecording(track) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package queue
+
+// SkipVote collects the votes to skip the song currently playing
+type SkipVote struct {
+	SongID string
+	Voters map[string]bool
+}
+
+// VoteSkip flips the vote of voteID to skip the song songID, which is currently playing.
+// Votes for a previous song are discarded.
+func (q *Queue) VoteSkip(songID string, voteID string) bool {
+	if q.Skip.SongID != songID || q.Skip.Voters == nil {
+		q.Skip = SkipVote{
+			SongID: songID,
+			Voters: make(map[string]bool),
+		}
+	}
+	q.Skip.Voters[voteID] = !q.Skip.Voters[voteID]
+	if !q.Skip.Voters[voteID] {
+		delete(q.Skip.Voters, voteID)
+	}
+	return q.Skip.Voters[voteID]
+}
+
+// SkipVotes returns the number of votes to skip the song songID
+func (q *Queue) SkipVotes(songID string) int {
+	if q.Skip.SongID != songID {
+		return 0
+	}
+	return len(q.Skip.Voters)
+}
+
+// HasSkipVote reports whether voteID voted to skip the song songID
+func (q *Queue) HasSkipVote(songID string, voteID string) bool {
+	return q.Skip.SongID == songID && q.Skip.Voters[voteID]
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+)
+
+type memoryEntry struct {
+	failures    int
+	windowEnd   time.Time
+	lockedUntil time.Time
+}
+
+// Memory is a Limiter for a single backend instance. Lockouts are measured with the clock.
+type Memory struct {
+	sync.Mutex
+	clock   clock.Clock
+	entries map[string]*memoryEntry
+}
+
+func NewMemory(c clock.Clock) *Memory {
+	return &Memory{
+		clock:   c,
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+func (m *Memory) Locked(key string) (time.Duration, error) {
+	m.Lock()
+	defer m.Unlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := entry.lockedUntil.Sub(m.clock.Now()); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+func (m *Memory) Fail(key string, policy Policy) (time.Duration, error) {
+	m.Lock()
+	defer m.Unlock()
+	now := m.clock.Now()
+	// Drop expired entries, so the limiter doesn't grow without bounds
+	for k, entry := range m.entries {
+		if now.After(entry.windowEnd) && now.After(entry.lockedUntil) {
+			delete(m.entries, k)
+		}
+	}
+
+	entry, ok := m.entries[key]
+	if !ok {
+		entry = &memoryEntry{windowEnd: now.Add(policy.Window)}
+		m.entries[key] = entry
+	}
+	entry.failures++
+	if entry.failures < policy.Attempts {
+		return 0, nil
+	}
+	entry.failures = 0
+	entry.windowEnd = now.Add(policy.Lockout)
+	entry.lockedUntil = now.Add(policy.Lockout)
+	return policy.Lockout, nil
+}
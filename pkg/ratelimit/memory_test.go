@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+)
+
+func TestMemory(t *testing.T) {
+	c := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := NewMemory(c)
+	policy := Policy{Attempts: 3, Window: time.Minute, Lockout: 5 * time.Minute}
+
+	// Failures, which fall into different windows, don't lock out the key
+	for i := 0; i < 2; i++ {
+		if lockout, err := m.Fail("key", policy); err != nil || lockout != 0 {
+			t.Fatalf("Fail = %v, %v, want no lockout", lockout, err)
+		}
+	}
+	c.Advance(policy.Window + time.Second)
+	if lockout, err := m.Fail("key", policy); err != nil || lockout != 0 {
+		t.Fatalf("Fail after the window = %v, %v, want no lockout", lockout, err)
+	}
+
+	// The third failure within the window locks out the key
+	for i := 0; i < 2; i++ {
+		if _, err := m.Fail("key", policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if remaining, err := m.Locked("key"); err != nil || remaining != policy.Lockout {
+		t.Fatalf("Locked = %v, %v, want %v", remaining, err, policy.Lockout)
+	}
+	if remaining, err := m.Locked("other"); err != nil || remaining != 0 {
+		t.Errorf("Locked of another key = %v, %v, want 0", remaining, err)
+	}
+
+	c.Advance(policy.Lockout - time.Minute)
+	if remaining, err := m.Locked("key"); err != nil || remaining != time.Minute {
+		t.Errorf("Locked = %v, %v, want %v", remaining, err, time.Minute)
+	}
+	c.Advance(time.Minute)
+	if remaining, err := m.Locked("key"); err != nil || remaining != 0 {
+		t.Errorf("Locked after the lockout = %v, %v, want 0", remaining, err)
+	}
+}
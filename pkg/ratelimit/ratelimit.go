@@ -0,0 +1,22 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Policy limits the failed attempts for a key. After Attempts failures within Window, the key is locked out
+// for Lockout.
+type Policy struct {
+	Attempts int
+	Window   time.Duration
+	Lockout  time.Duration
+}
+
+// Limiter counts failed attempts, e.g. to join a JamSession with a wrong password, and locks out keys with too
+// many failures
+type Limiter interface {
+	// Locked returns the remaining lockout of key, or 0 if key isn't locked out
+	Locked(key string) (time.Duration, error)
+	// Fail records a failed attempt for key and returns the lockout of key, if the attempt exceeded the policy
+	Fail(key string, policy Policy) (time.Duration, error)
+}
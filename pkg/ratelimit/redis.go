@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	pkgredis "github.com/jamfactoryapp/jamfactory-backend/internal/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+// failScript counts a failure within the window and sets the lock, once the attempts are exceeded.
+// It returns the lockout in milliseconds or 0.
+var failScript = redis.NewScript(2, `
+local failures = redis.call("INCR", KEYS[1])
+if failures == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+if failures < tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+redis.call("SET", KEYS[2], 1, "PX", ARGV[3])
+return tonumber(ARGV[3])
+`)
+
+// Redis is a Limiter shared by all backend instances using the same redis
+type Redis struct {
+	pool     *redis.Pool
+	redisKey pkgredis.Key
+}
+
+func NewRedis(pool *redis.Pool) *Redis {
+	return &Redis{
+		pool:     pool,
+		redisKey: pkgredis.NewKey("ratelimit"),
+	}
+}
+
+func (r *Redis) Locked(key string) (time.Duration, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+	remaining, err := redis.Int64(conn.Do("PTTL", r.redisKey.Append("locked").Append(key).String()))
+	if err != nil || remaining < 0 {
+		return 0, err
+	}
+	return time.Duration(remaining) * time.Millisecond, nil
+}
+
+func (r *Redis) Fail(key string, policy Policy) (time.Duration, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+	lockout, err := redis.Int64(failScript.Do(conn,
+		r.redisKey.Append("failures").Append(key).String(), r.redisKey.Append("locked").Append(key).String(),
+		policy.Attempts, policy.Window.Milliseconds(), policy.Lockout.Milliseconds()))
+	log.Trace("redis ratelimit FAIL for: ", key, " lockout: ", lockout, " with err: ", err)
+	return time.Duration(lockout) * time.Millisecond, err
+}
@@ -3,22 +3,26 @@ package song
 import (
 	"time"
 
-	"github.com/zmb3/spotify/v2"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 )
 
 type Song struct {
-	ID    string
-	Track *spotify.FullTrack
-	Votes map[string]bool
-	Date  time.Time
+	ID        string
+	Track     *provider.Track
+	Votes     map[string]bool
+	Downvotes map[string]bool
+	Date      time.Time
+	Submitter string
 }
 
-func New(t *spotify.FullTrack) *Song {
+func New(t *provider.Track, submitter string) *Song {
 	return &Song{
-		Track: t,
-		ID:    string(t.ID),
-		Votes: make(map[string]bool),
-		Date:  time.Now(),
+		Track:     t,
+		ID:        t.ID,
+		Votes:     make(map[string]bool),
+		Downvotes: make(map[string]bool),
+		Date:      time.Now(),
+		Submitter: submitter,
 	}
 }
 
@@ -34,6 +38,21 @@ func (s *Song) GetVotes() []string {
 	return votes
 }
 
+func (s *Song) GetDownvotes() []string {
+	var downvotes []string
+	for v, downvoted := range s.Downvotes {
+		if downvoted {
+			downvotes = append(downvotes, v)
+		}
+	}
+	return downvotes
+}
+
+// Score is the number of votes minus the number of downvotes
+func (s *Song) Score() int {
+	return len(s.GetVotes()) - len(s.GetDownvotes())
+}
+
 func (s *Song) Vote(voteID string) bool {
 	if _, exists := s.Votes[voteID]; !exists {
 		// create new vote
@@ -42,11 +61,31 @@ func (s *Song) Vote(voteID string) bool {
 		// flip vote state
 		s.Votes[voteID] = !s.Votes[voteID]
 	}
+	// a vote replaces a downvote
+	if s.Votes[voteID] && s.HasDownvote(voteID) {
+		s.Downvotes[voteID] = false
+	}
 	// return new vote state
 	return s.Votes[voteID]
 }
 
+// Downvote flips the downvote state of voteID. A downvote replaces a vote
+func (s *Song) Downvote(voteID string) bool {
+	if s.Downvotes == nil {
+		s.Downvotes = make(map[string]bool)
+	}
+	s.Downvotes[voteID] = !s.Downvotes[voteID]
+	if s.Downvotes[voteID] && s.HasVote(voteID) {
+		s.Votes[voteID] = false
+	}
+	return s.Downvotes[voteID]
+}
+
 func (s *Song) HasVote(voteID string) bool {
 	x, ok := s.Votes[voteID]
 	return ok && x
 }
+
+func (s *Song) HasDownvote(voteID string) bool {
+	return s.Downvotes[voteID]
+}
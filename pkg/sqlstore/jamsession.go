@@ -0,0 +1,301 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/jamsession"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/permissions"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
+)
+
+// SettingsStore is a store.Store for jamsession.Settings persisted in the jam_sessions, jam_bans, jam_invites and
+// jam_pending tables
+type SettingsStore struct {
+	db *DB
+}
+
+func (s *DB) Settings() *SettingsStore {
+	return &SettingsStore{db: s}
+}
+
+func (s *SettingsStore) Get(key string) (*jamsession.Settings, error) {
+	return s.get(s.db.db, key)
+}
+
+func (s *SettingsStore) get(q querier, key string) (*jamsession.Settings, error) {
+	settings := &jamsession.Settings{}
+	var window, cooldown, replayCooldown, leadTime int64
+	var filter string
+	err := q.QueryRow(s.db.rebind(`SELECT name, active, password, ordering, downvote_threshold, skip_percentage, auto_handover,
+		require_approval, max_pending, max_additions, addition_window, addition_cooldown, content_filter,
+		replay_cooldown, replay_songs, fallback_type, fallback_id, lead_time FROM jam_sessions WHERE label = ?`), key).
+		Scan(&settings.Name, &settings.Active, &settings.Password, &settings.Ordering, &settings.DownvoteThreshold, &settings.SkipPercentage,
+			&settings.AutoHandover, &settings.RequireApproval, &settings.Quota.MaxPending, &settings.Quota.MaxAdditions, &window, &cooldown, &filter,
+			&replayCooldown, &settings.Replay.Songs, &settings.Fallback.Type, &settings.Fallback.ID, &leadTime)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrObjNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	settings.Quota.Window = time.Duration(window)
+	settings.Quota.Cooldown = time.Duration(cooldown)
+	settings.Replay.Cooldown = time.Duration(replayCooldown)
+	settings.LeadTime = time.Duration(leadTime)
+	if err := json.Unmarshal([]byte(filter), &settings.Filter); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(s.db.rebind("SELECT identifier FROM jam_bans WHERE label = ? ORDER BY position"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var identifier string
+		if err := rows.Scan(&identifier); err != nil {
+			return nil, err
+		}
+		settings.Bans = append(settings.Bans, identifier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	inviteRows, err := q.Query(s.db.rebind("SELECT id, expires_at, single_use FROM jam_invites WHERE label = ? ORDER BY expires_at"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer inviteRows.Close()
+	for inviteRows.Next() {
+		var invite jamsession.Invite
+		var expiresAt int64
+		if err := inviteRows.Scan(&invite.ID, &expiresAt, &invite.SingleUse); err != nil {
+			return nil, err
+		}
+		invite.ExpiresAt = time.Unix(0, expiresAt)
+		settings.Invites = append(settings.Invites, invite)
+	}
+	if err := inviteRows.Err(); err != nil {
+		return nil, err
+	}
+
+	pendingRows, err := q.Query(s.db.rebind("SELECT identifier FROM jam_pending WHERE label = ? ORDER BY position"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer pendingRows.Close()
+	for pendingRows.Next() {
+		var identifier string
+		if err := pendingRows.Scan(&identifier); err != nil {
+			return nil, err
+		}
+		settings.Pending = append(settings.Pending, identifier)
+	}
+	if err := pendingRows.Err(); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *SettingsStore) GetAll() ([]*jamsession.Settings, error) {
+	keys, err := s.db.keys("SELECT label FROM jam_sessions")
+	if err != nil {
+		return nil, err
+	}
+	return getAll[jamsession.Settings](s, keys)
+}
+
+func (s *SettingsStore) Save(settings *jamsession.Settings, key string) error {
+	return s.db.tx(func(tx *sql.Tx) error {
+		return s.save(tx, settings, key)
+	})
+}
+
+func (s *SettingsStore) Update(key string, fn func(settings *jamsession.Settings) error) error {
+	return update(s.db, "jam_sessions", key, s.get, s.save, fn)
+}
+
+func (s *SettingsStore) save(q querier, settings *jamsession.Settings, key string) error {
+	filter, err := json.Marshal(settings.Filter)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(s.db.rebind(`INSERT INTO jam_sessions (label, name, active, password, ordering, downvote_threshold, skip_percentage, auto_handover,
+		require_approval, max_pending, max_additions, addition_window, addition_cooldown, content_filter,
+		replay_cooldown, replay_songs, fallback_type, fallback_id, lead_time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (label) DO UPDATE SET name = excluded.name, active = excluded.active, password = excluded.password,
+		ordering = excluded.ordering, downvote_threshold = excluded.downvote_threshold, skip_percentage = excluded.skip_percentage,
+		auto_handover = excluded.auto_handover, require_approval = excluded.require_approval, max_pending = excluded.max_pending,
+		max_additions = excluded.max_additions, addition_window = excluded.addition_window, addition_cooldown = excluded.addition_cooldown,
+		content_filter = excluded.content_filter, replay_cooldown = excluded.replay_cooldown, replay_songs = excluded.replay_songs,
+		fallback_type = excluded.fallback_type, fallback_id = excluded.fallback_id, lead_time = excluded.lead_time`),
+		key, settings.Name, settings.Active, settings.Password, settings.Ordering.Effective(), settings.DownvoteThreshold,
+		settings.SkipPercentage, settings.AutoHandover, settings.RequireApproval, settings.Quota.MaxPending, settings.Quota.MaxAdditions,
+		int64(settings.Quota.Window), int64(settings.Quota.Cooldown), string(filter),
+		int64(settings.Replay.Cooldown), settings.Replay.Songs, settings.Fallback.Type, settings.Fallback.ID,
+		int64(settings.LeadTime))
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.Exec(s.db.rebind("DELETE FROM jam_bans WHERE label = ?"), key); err != nil {
+		return err
+	}
+	for position, identifier := range settings.Bans {
+		if _, err := q.Exec(s.db.rebind("INSERT INTO jam_bans (label, identifier, position) VALUES (?, ?, ?)"),
+			key, identifier, position); err != nil {
+			return err
+		}
+	}
+
+	if _, err := q.Exec(s.db.rebind("DELETE FROM jam_invites WHERE label = ?"), key); err != nil {
+		return err
+	}
+	for _, invite := range settings.Invites {
+		if _, err := q.Exec(s.db.rebind("INSERT INTO jam_invites (label, id, expires_at, single_use) VALUES (?, ?, ?, ?)"),
+			key, invite.ID, invite.ExpiresAt.UnixNano(), invite.SingleUse); err != nil {
+			return err
+		}
+	}
+
+	if _, err := q.Exec(s.db.rebind("DELETE FROM jam_pending WHERE label = ?"), key); err != nil {
+		return err
+	}
+	for position, identifier := range settings.Pending {
+		if _, err := q.Exec(s.db.rebind("INSERT INTO jam_pending (label, identifier, position) VALUES (?, ?, ?)"),
+			key, identifier, position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SettingsStore) Delete(key string) error {
+	return s.db.tx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(s.db.rebind("DELETE FROM jam_bans WHERE label = ?"), key); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(s.db.rebind("DELETE FROM jam_invites WHERE label = ?"), key); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(s.db.rebind("DELETE FROM jam_pending WHERE label = ?"), key); err != nil {
+			return err
+		}
+		_, err := tx.Exec(s.db.rebind("DELETE FROM jam_sessions WHERE label = ?"), key)
+		return err
+	})
+}
+
+// MembersStore is a store.Store for jamsession.Members persisted in the jam_members and jam_member_permissions tables
+type MembersStore struct {
+	db *DB
+}
+
+func (s *DB) Members() *MembersStore {
+	return &MembersStore{db: s}
+}
+
+func (s *MembersStore) Get(key string) (*jamsession.Members, error) {
+	var members *jamsession.Members
+	err := s.db.tx(func(tx *sql.Tx) error {
+		var err error
+		members, err = s.get(tx, key)
+		return err
+	})
+	return members, err
+}
+
+func (s *MembersStore) get(q querier, key string) (*jamsession.Members, error) {
+	members := jamsession.Members{}
+	rows, err := q.Query(s.db.rebind("SELECT identifier FROM jam_members WHERE label = ?"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var identifier string
+		if err := rows.Scan(&identifier); err != nil {
+			return nil, err
+		}
+		members[identifier] = jamsession.NewMember(identifier)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, store.ErrObjNotFound
+	}
+
+	permissionRows, err := q.Query(s.db.rebind("SELECT identifier, permission FROM jam_member_permissions WHERE label = ?"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer permissionRows.Close()
+	for permissionRows.Next() {
+		var identifier, permission string
+		if err := permissionRows.Scan(&identifier, &permission); err != nil {
+			return nil, err
+		}
+		if member, ok := members[identifier]; ok {
+			member.AddPermissions(permissions.Permission(permission))
+		}
+	}
+	if err := permissionRows.Err(); err != nil {
+		return nil, err
+	}
+	return &members, nil
+}
+
+func (s *MembersStore) GetAll() ([]*jamsession.Members, error) {
+	keys, err := s.db.keys("SELECT DISTINCT label FROM jam_members")
+	if err != nil {
+		return nil, err
+	}
+	return getAll[jamsession.Members](s, keys)
+}
+
+func (s *MembersStore) Save(members *jamsession.Members, key string) error {
+	return s.db.tx(func(tx *sql.Tx) error {
+		return s.save(tx, members, key)
+	})
+}
+
+func (s *MembersStore) Update(key string, fn func(members *jamsession.Members) error) error {
+	return update(s.db, "jam_members", key, s.get, s.save, fn)
+}
+
+func (s *MembersStore) save(q querier, members *jamsession.Members, key string) error {
+	if err := s.delete(q, key); err != nil {
+		return err
+	}
+	for identifier, member := range *members {
+		if _, err := q.Exec(s.db.rebind("INSERT INTO jam_members (label, identifier) VALUES (?, ?)"), key, identifier); err != nil {
+			return err
+		}
+		for _, permission := range member.GetPermissions() {
+			if _, err := q.Exec(s.db.rebind("INSERT INTO jam_member_permissions (label, identifier, permission) VALUES (?, ?, ?)"),
+				key, identifier, string(permission)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *MembersStore) Delete(key string) error {
+	return s.db.tx(func(tx *sql.Tx) error {
+		return s.delete(tx, key)
+	})
+}
+
+func (s *MembersStore) delete(q querier, key string) error {
+	if _, err := q.Exec(s.db.rebind("DELETE FROM jam_member_permissions WHERE label = ?"), key); err != nil {
+		return err
+	}
+	_, err := q.Exec(s.db.rebind("DELETE FROM jam_members WHERE label = ?"), key)
+	return err
+}
@@ -0,0 +1,71 @@
+CREATE TABLE sets (
+    name   TEXT NOT NULL,
+    member TEXT NOT NULL,
+    PRIMARY KEY (name, member)
+);
+
+CREATE TABLE users (
+    identifier    TEXT PRIMARY KEY,
+    user_type     TEXT NOT NULL,
+    user_name     TEXT NOT NULL,
+    spotify_token TEXT
+);
+
+CREATE TABLE sessions (
+    id         TEXT PRIMARY KEY,
+    data       TEXT   NOT NULL,
+    expires_at BIGINT NOT NULL
+);
+
+CREATE TABLE jam_sessions (
+    label    TEXT PRIMARY KEY,
+    name     TEXT    NOT NULL,
+    active   BOOLEAN NOT NULL,
+    password TEXT    NOT NULL
+);
+
+CREATE TABLE jam_members (
+    label      TEXT NOT NULL,
+    identifier TEXT NOT NULL,
+    PRIMARY KEY (label, identifier)
+);
+
+CREATE TABLE jam_member_permissions (
+    label      TEXT NOT NULL,
+    identifier TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    PRIMARY KEY (label, identifier, permission)
+);
+
+CREATE TABLE queues (
+    label TEXT PRIMARY KEY
+);
+
+CREATE TABLE queue_songs (
+    label    TEXT    NOT NULL,
+    song_id  TEXT    NOT NULL,
+    position INTEGER NOT NULL,
+    track    TEXT    NOT NULL,
+    added_at BIGINT  NOT NULL,
+    PRIMARY KEY (label, song_id)
+);
+
+CREATE TABLE queue_votes (
+    label   TEXT    NOT NULL,
+    song_id TEXT    NOT NULL,
+    voter   TEXT    NOT NULL,
+    voted   BOOLEAN NOT NULL,
+    PRIMARY KEY (label, song_id, voter)
+);
+
+CREATE TABLE played_songs (
+    label    TEXT    NOT NULL,
+    position INTEGER NOT NULL,
+    song_id  TEXT    NOT NULL,
+    track    TEXT    NOT NULL,
+    added_at BIGINT  NOT NULL,
+    votes    INTEGER NOT NULL,
+    PRIMARY KEY (label, position)
+);
+
+CREATE INDEX played_songs_song_id ON played_songs (label, song_id);
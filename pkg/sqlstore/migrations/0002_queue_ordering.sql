@@ -0,0 +1,5 @@
+ALTER TABLE jam_sessions ADD COLUMN ordering TEXT NOT NULL DEFAULT 'votes';
+
+ALTER TABLE queue_songs ADD COLUMN submitter TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE played_songs ADD COLUMN submitter TEXT NOT NULL DEFAULT '';
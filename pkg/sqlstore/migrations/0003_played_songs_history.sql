@@ -0,0 +1,3 @@
+ALTER TABLE played_songs ADD COLUMN started_at BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE played_songs ADD COLUMN skipped BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,12 @@
+ALTER TABLE jam_sessions ADD COLUMN downvote_threshold INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jam_sessions ADD COLUMN skip_percentage INTEGER NOT NULL DEFAULT 50;
+
+ALTER TABLE queue_votes ADD COLUMN downvoted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE queue_skip_votes (
+    label   TEXT NOT NULL,
+    song_id TEXT NOT NULL,
+    voter   TEXT NOT NULL,
+    PRIMARY KEY (label, voter)
+);
@@ -0,0 +1 @@
+ALTER TABLE jam_sessions ADD COLUMN auto_handover BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+CREATE TABLE jam_bans (
+    label      TEXT    NOT NULL,
+    identifier TEXT    NOT NULL,
+    position   INTEGER NOT NULL,
+    PRIMARY KEY (label, identifier)
+);
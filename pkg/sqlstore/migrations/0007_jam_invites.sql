@@ -0,0 +1,7 @@
+CREATE TABLE jam_invites (
+    label      TEXT    NOT NULL,
+    id         TEXT    NOT NULL,
+    expires_at BIGINT  NOT NULL,
+    single_use BOOLEAN NOT NULL,
+    PRIMARY KEY (label, id)
+);
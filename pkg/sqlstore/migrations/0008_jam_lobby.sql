@@ -0,0 +1,8 @@
+ALTER TABLE jam_sessions ADD COLUMN require_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE jam_pending (
+    label      TEXT    NOT NULL,
+    identifier TEXT    NOT NULL,
+    position   INTEGER NOT NULL,
+    PRIMARY KEY (label, identifier)
+);
@@ -0,0 +1,15 @@
+ALTER TABLE jam_sessions ADD COLUMN max_pending INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jam_sessions ADD COLUMN max_additions INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE jam_sessions ADD COLUMN addition_window BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE jam_sessions ADD COLUMN addition_cooldown BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE queue_additions (
+    label     TEXT    NOT NULL,
+    position  INTEGER NOT NULL,
+    submitter TEXT    NOT NULL,
+    added_at  BIGINT  NOT NULL,
+    PRIMARY KEY (label, position)
+);
@@ -0,0 +1 @@
+ALTER TABLE jam_sessions ADD COLUMN content_filter TEXT NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+ALTER TABLE jam_sessions ADD COLUMN replay_cooldown BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE jam_sessions ADD COLUMN replay_songs INTEGER NOT NULL DEFAULT 0;
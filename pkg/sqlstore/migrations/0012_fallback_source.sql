@@ -0,0 +1,12 @@
+ALTER TABLE jam_sessions ADD COLUMN fallback_type TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE jam_sessions ADD COLUMN fallback_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE played_songs ADD COLUMN fallback BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE queue_fallback (
+    label    TEXT    NOT NULL,
+    position INTEGER NOT NULL,
+    track    TEXT    NOT NULL,
+    PRIMARY KEY (label, position)
+);
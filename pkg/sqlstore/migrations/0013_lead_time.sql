@@ -0,0 +1 @@
+ALTER TABLE jam_sessions ADD COLUMN lead_time BIGINT NOT NULL DEFAULT 0;
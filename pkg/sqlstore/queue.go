@@ -0,0 +1,304 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/queue"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/song"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
+)
+
+// QueueStore is a store.Store for queue.Queue persisted in the queue_songs, queue_votes, queue_skip_votes,
+// queue_additions, queue_fallback and played_songs tables
+type QueueStore struct {
+	db *DB
+}
+
+func (s *DB) Queues() *QueueStore {
+	return &QueueStore{db: s}
+}
+
+func (s *QueueStore) Get(key string) (*queue.Queue, error) {
+	var q *queue.Queue
+	err := s.db.tx(func(tx *sql.Tx) error {
+		var err error
+		q, err = s.get(tx, key)
+		return err
+	})
+	return q, err
+}
+
+func (s *QueueStore) get(db querier, key string) (*queue.Queue, error) {
+	var label string
+	err := db.QueryRow(s.db.rebind("SELECT label FROM queues WHERE label = ?"), key).Scan(&label)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrObjNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	q := queue.New()
+	songs := make(map[string]*song.Song)
+	rows, err := db.Query(s.db.rebind("SELECT song_id, track, added_at, submitter FROM queue_songs WHERE label = ? ORDER BY position"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		so, err := scanSong(rows)
+		if err != nil {
+			return nil, err
+		}
+		songs[so.ID] = so
+		q.Songs = append(q.Songs, so)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	voteRows, err := db.Query(s.db.rebind("SELECT song_id, voter, voted, downvoted FROM queue_votes WHERE label = ?"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer voteRows.Close()
+	for voteRows.Next() {
+		var songID, voter string
+		var voted, downvoted bool
+		if err := voteRows.Scan(&songID, &voter, &voted, &downvoted); err != nil {
+			return nil, err
+		}
+		if so, ok := songs[songID]; ok {
+			so.Votes[voter] = voted
+			if downvoted {
+				so.Downvotes[voter] = true
+			}
+		}
+	}
+	if err := voteRows.Err(); err != nil {
+		return nil, err
+	}
+
+	skipRows, err := db.Query(s.db.rebind("SELECT song_id, voter FROM queue_skip_votes WHERE label = ?"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer skipRows.Close()
+	for skipRows.Next() {
+		var songID, voter string
+		if err := skipRows.Scan(&songID, &voter); err != nil {
+			return nil, err
+		}
+		if q.Skip.Voters == nil {
+			q.Skip = queue.SkipVote{SongID: songID, Voters: make(map[string]bool)}
+		}
+		q.Skip.Voters[voter] = true
+	}
+	if err := skipRows.Err(); err != nil {
+		return nil, err
+	}
+
+	historyRows, err := db.Query(s.db.rebind("SELECT song_id, track, added_at, submitter, votes, started_at, skipped, fallback FROM played_songs WHERE label = ? ORDER BY position"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer historyRows.Close()
+	for historyRows.Next() {
+		played, err := scanPlayedSong(historyRows)
+		if err != nil {
+			return nil, err
+		}
+		q.History = append(q.History, played)
+	}
+	if err := historyRows.Err(); err != nil {
+		return nil, err
+	}
+
+	additionRows, err := db.Query(s.db.rebind("SELECT submitter, added_at FROM queue_additions WHERE label = ? ORDER BY position"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer additionRows.Close()
+	for additionRows.Next() {
+		var addition queue.Addition
+		var addedAt int64
+		if err := additionRows.Scan(&addition.Submitter, &addedAt); err != nil {
+			return nil, err
+		}
+		addition.At = time.Unix(0, addedAt)
+		q.Additions = append(q.Additions, addition)
+	}
+	if err := additionRows.Err(); err != nil {
+		return nil, err
+	}
+
+	fallbackRows, err := db.Query(s.db.rebind("SELECT track FROM queue_fallback WHERE label = ? ORDER BY position"), key)
+	if err != nil {
+		return nil, err
+	}
+	defer fallbackRows.Close()
+	for fallbackRows.Next() {
+		var trackData string
+		if err := fallbackRows.Scan(&trackData); err != nil {
+			return nil, err
+		}
+		track := &provider.Track{}
+		if err := json.Unmarshal([]byte(trackData), track); err != nil {
+			return nil, err
+		}
+		q.Fallback = append(q.Fallback, track)
+	}
+	if err := fallbackRows.Err(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (s *QueueStore) GetAll() ([]*queue.Queue, error) {
+	keys, err := s.db.keys("SELECT label FROM queues")
+	if err != nil {
+		return nil, err
+	}
+	return getAll[queue.Queue](s, keys)
+}
+
+func (s *QueueStore) Save(q *queue.Queue, key string) error {
+	return s.db.tx(func(tx *sql.Tx) error {
+		return s.save(tx, q, key)
+	})
+}
+
+func (s *QueueStore) Update(key string, fn func(q *queue.Queue) error) error {
+	return update(s.db, "queues", key, s.get, s.save, fn)
+}
+
+func (s *QueueStore) save(db querier, q *queue.Queue, key string) error {
+	if err := s.delete(db, key); err != nil {
+		return err
+	}
+	if _, err := db.Exec(s.db.rebind("INSERT INTO queues (label) VALUES (?)"), key); err != nil {
+		return err
+	}
+	for i, so := range q.Songs {
+		track, err := json.Marshal(so.Track)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(s.db.rebind("INSERT INTO queue_songs (label, song_id, position, track, added_at, submitter) VALUES (?, ?, ?, ?, ?, ?)"),
+			key, so.ID, i, string(track), so.Date.UnixNano(), so.Submitter); err != nil {
+			return err
+		}
+		for voter, voted := range so.Votes {
+			if _, err := db.Exec(s.db.rebind("INSERT INTO queue_votes (label, song_id, voter, voted, downvoted) VALUES (?, ?, ?, ?, ?)"),
+				key, so.ID, voter, voted, so.HasDownvote(voter)); err != nil {
+				return err
+			}
+		}
+		for voter, downvoted := range so.Downvotes {
+			if _, voted := so.Votes[voter]; voted || !downvoted {
+				continue
+			}
+			if _, err := db.Exec(s.db.rebind("INSERT INTO queue_votes (label, song_id, voter, voted, downvoted) VALUES (?, ?, ?, ?, ?)"),
+				key, so.ID, voter, false, true); err != nil {
+				return err
+			}
+		}
+	}
+	for voter, skip := range q.Skip.Voters {
+		if !skip {
+			continue
+		}
+		if _, err := db.Exec(s.db.rebind("INSERT INTO queue_skip_votes (label, song_id, voter) VALUES (?, ?, ?)"),
+			key, q.Skip.SongID, voter); err != nil {
+			return err
+		}
+	}
+	for i, played := range q.History {
+		track, err := json.Marshal(played.Track)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(s.db.rebind("INSERT INTO played_songs (label, position, song_id, track, added_at, votes, submitter, started_at, skipped, fallback) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+			key, i, played.ID, string(track), played.AddedAt.UnixNano(), played.Votes, played.Submitter, played.StartedAt.UnixNano(), played.Skipped, played.Fallback); err != nil {
+			return err
+		}
+	}
+	for i, addition := range q.Additions {
+		if _, err := db.Exec(s.db.rebind("INSERT INTO queue_additions (label, position, submitter, added_at) VALUES (?, ?, ?, ?)"),
+			key, i, addition.Submitter, addition.At.UnixNano()); err != nil {
+			return err
+		}
+	}
+	for i, fallback := range q.Fallback {
+		track, err := json.Marshal(fallback)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(s.db.rebind("INSERT INTO queue_fallback (label, position, track) VALUES (?, ?, ?)"),
+			key, i, string(track)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *QueueStore) Delete(key string) error {
+	return s.db.tx(func(tx *sql.Tx) error {
+		return s.delete(tx, key)
+	})
+}
+
+func (s *QueueStore) delete(db querier, key string) error {
+	for _, query := range []string{
+		"DELETE FROM queue_votes WHERE label = ?",
+		"DELETE FROM queue_skip_votes WHERE label = ?",
+		"DELETE FROM queue_songs WHERE label = ?",
+		"DELETE FROM played_songs WHERE label = ?",
+		"DELETE FROM queue_additions WHERE label = ?",
+		"DELETE FROM queue_fallback WHERE label = ?",
+		"DELETE FROM queues WHERE label = ?",
+	} {
+		if _, err := db.Exec(s.db.rebind(query), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// scanSong scans a row of song_id, track, added_at and submitter into a song.Song
+func scanSong(rows *sql.Rows) (*song.Song, error) {
+	var songID, trackData, submitter string
+	var addedAt int64
+	if err := rows.Scan(&songID, &trackData, &addedAt, &submitter); err != nil {
+		return nil, err
+	}
+	track := &provider.Track{}
+	if err := json.Unmarshal([]byte(trackData), track); err != nil {
+		return nil, err
+	}
+	so := song.New(track, submitter)
+	so.ID = songID
+	so.Date = time.Unix(0, addedAt)
+	return so, nil
+}
+
+// scanPlayedSong scans a row of song_id, track, added_at, submitter, votes, started_at, skipped and fallback into a
+// queue.PlayedSong
+func scanPlayedSong(rows *sql.Rows) (*queue.PlayedSong, error) {
+	var trackData string
+	var addedAt, startedAt int64
+	played := &queue.PlayedSong{}
+	if err := rows.Scan(&played.ID, &trackData, &addedAt, &played.Submitter, &played.Votes, &startedAt, &played.Skipped, &played.Fallback); err != nil {
+		return nil, err
+	}
+	played.Track = &provider.Track{}
+	if err := json.Unmarshal([]byte(trackData), played.Track); err != nil {
+		return nil, err
+	}
+	played.AddedAt = time.Unix(0, addedAt)
+	played.StartedAt = time.Unix(0, startedAt)
+	return played, nil
+}
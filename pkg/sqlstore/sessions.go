@@ -0,0 +1,50 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"time"
+)
+
+// SessionBackend persists http sessions in the sessions table.
+// It implements the Backend of the api/sessions package.
+type SessionBackend struct {
+	db *DB
+}
+
+func (s *DB) Sessions() *SessionBackend {
+	return &SessionBackend{db: s}
+}
+
+func (b *SessionBackend) Load(id string) ([]byte, error) {
+	var data string
+	var expiresAt int64
+	err := b.db.db.QueryRow(b.db.rebind("SELECT data, expires_at FROM sessions WHERE id = ?"), id).Scan(&data, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Unix() > expiresAt {
+		return nil, b.Delete(id)
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
+
+func (b *SessionBackend) Save(id string, data []byte, maxAge int) error {
+	now := time.Now()
+	// Drop expired sessions, so the table doesn't grow without bounds
+	if _, err := b.db.db.Exec(b.db.rebind("DELETE FROM sessions WHERE expires_at < ?"), now.Unix()); err != nil {
+		return err
+	}
+	_, err := b.db.db.Exec(b.db.rebind(`INSERT INTO sessions (id, data, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data, expires_at = excluded.expires_at`),
+		id, base64.StdEncoding.EncodeToString(data), now.Add(time.Duration(maxAge)*time.Second).Unix())
+	return err
+}
+
+func (b *SessionBackend) Delete(id string) error {
+	_, err := b.db.db.Exec(b.db.rebind("DELETE FROM sessions WHERE id = ?"), id)
+	return err
+}
@@ -0,0 +1,54 @@
+package sqlstore
+
+import (
+	"database/sql"
+)
+
+// Set is a store.Set persisted in the sets table
+type Set struct {
+	db   *DB
+	name string
+}
+
+func (s *DB) Set(name string) *Set {
+	return &Set{
+		db:   s,
+		name: name,
+	}
+}
+
+func (s *Set) GetAll() ([]string, error) {
+	rows, err := s.db.db.Query(s.db.rebind("SELECT member FROM sets WHERE name = ?"), s.name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	members := make([]string, 0)
+	for rows.Next() {
+		var member string
+		if err := rows.Scan(&member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *Set) Add(key string) error {
+	_, err := s.db.db.Exec(s.db.rebind("INSERT INTO sets (name, member) VALUES (?, ?) ON CONFLICT DO NOTHING"), s.name, key)
+	return err
+}
+
+func (s *Set) Has(key string) (bool, error) {
+	var member string
+	err := s.db.db.QueryRow(s.db.rebind("SELECT member FROM sets WHERE name = ? AND member = ?"), s.name, key).Scan(&member)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *Set) Delete(key string) error {
+	_, err := s.db.db.Exec(s.db.rebind("DELETE FROM sets WHERE name = ? AND member = ?"), s.name, key)
+	return err
+}
@@ -0,0 +1,204 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	log "github.com/sirupsen/logrus"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+var (
+	ErrDriverInvalid = errors.New("sqlstore: invalid driver")
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// DB is a SQL database holding users, sessions and JamSessions.
+// SQLite and Postgres are supported, queries are written for SQLite and rebound for Postgres.
+type DB struct {
+	db     *sql.DB
+	driver string
+}
+
+func Open(driver string, dsn string) (*DB, error) {
+	var sqlDriver string
+	switch driver {
+	case DriverSQLite:
+		sqlDriver = "sqlite"
+	case DriverPostgres:
+		sqlDriver = "pgx"
+	default:
+		return nil, ErrDriverInvalid
+	}
+
+	db, err := sql.Open(sqlDriver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverSQLite {
+		// SQLite only allows a single writer. Serializing all access avoids SQLITE_BUSY errors
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	s := &DB{
+		db:     db,
+		driver: driver,
+	}
+	if err := s.migrate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *DB) Close() error {
+	return s.db.Close()
+}
+
+// migrate applies all embedded migrations, which have not been applied yet
+func (s *DB) migrate() error {
+	if _, err := s.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)"); err != nil {
+		return err
+	}
+
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, entry := range entries {
+		version, err := strconv.Atoi(strings.SplitN(entry.Name(), "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("sqlstore: malformed migration name %s", entry.Name())
+		}
+
+		var applied int
+		if err := s.db.QueryRow(s.rebind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), version).Scan(&applied); err != nil {
+			return err
+		}
+		if applied > 0 {
+			continue
+		}
+
+		migration, err := migrations.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return err
+		}
+		err = s.tx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(string(migration)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(s.rebind("INSERT INTO schema_migrations (version) VALUES (?)"), version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("sqlstore: migration %s failed: %w", entry.Name(), err)
+		}
+		log.Info("Applied database migration ", entry.Name())
+	}
+	return nil
+}
+
+// rebind replaces the ? placeholders with the placeholders of the used driver
+func (s *DB) rebind(query string) string {
+	if s.driver != DriverPostgres {
+		return query
+	}
+	var builder strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			builder.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// tx runs fn in a transaction, which is committed if fn returns no error
+func (s *DB) tx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			log.Warn("sqlstore: rollback failed: ", rollbackErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// lock locks the object of kind stored under key until tx ends.
+// SQLite only uses a single connection, so transactions are serialized anyway.
+func (s *DB) lock(tx *sql.Tx, kind string, key string) error {
+	if s.driver != DriverPostgres {
+		return nil
+	}
+	_, err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext($1))", kind+":"+key)
+	return err
+}
+
+// update implements store.Store.Update using the get and save functions of a store
+func update[T any](s *DB, kind string, key string, get func(q querier, key string) (*T, error), save func(q querier, obj *T, key string) error, fn func(obj *T) error) error {
+	return s.tx(func(tx *sql.Tx) error {
+		if err := s.lock(tx, kind, key); err != nil {
+			return err
+		}
+		obj, err := get(tx, key)
+		if err != nil {
+			return err
+		}
+		if err := fn(obj); err != nil {
+			return err
+		}
+		return save(tx, obj, key)
+	})
+}
+
+// keys returns the values of the single column selected by query
+func (s *DB) keys(query string) ([]string, error) {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
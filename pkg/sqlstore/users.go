@@ -0,0 +1,97 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/users"
+	"golang.org/x/oauth2"
+)
+
+// UserStore is a store.Store for users.UserInformation persisted in the users table
+type UserStore struct {
+	db *DB
+}
+
+func (s *DB) Users() *UserStore {
+	return &UserStore{db: s}
+}
+
+func (s *UserStore) Get(key string) (*users.UserInformation, error) {
+	return s.get(s.db.db, key)
+}
+
+func (s *UserStore) get(q querier, key string) (*users.UserInformation, error) {
+	var userType, userName string
+	var token sql.NullString
+	err := q.QueryRow(s.db.rebind("SELECT user_type, user_name, spotify_token FROM users WHERE identifier = ?"), key).
+		Scan(&userType, &userName, &token)
+	if err == sql.ErrNoRows {
+		return nil, store.ErrObjNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := &users.UserInformation{
+		UserType: users.UserType(userType),
+		UserName: userName,
+	}
+	if token.Valid {
+		info.SpotifyToken = &oauth2.Token{}
+		if err := json.Unmarshal([]byte(token.String), info.SpotifyToken); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+func (s *UserStore) GetAll() ([]*users.UserInformation, error) {
+	keys, err := s.db.keys("SELECT identifier FROM users")
+	if err != nil {
+		return nil, err
+	}
+	return getAll[users.UserInformation](s, keys)
+}
+
+func (s *UserStore) Save(info *users.UserInformation, key string) error {
+	return s.save(s.db.db, info, key)
+}
+
+func (s *UserStore) Update(key string, fn func(info *users.UserInformation) error) error {
+	return update(s.db, "users", key, s.get, s.save, fn)
+}
+
+func (s *UserStore) save(q querier, info *users.UserInformation, key string) error {
+	var token sql.NullString
+	if info.SpotifyToken != nil {
+		data, err := json.Marshal(info.SpotifyToken)
+		if err != nil {
+			return err
+		}
+		token = sql.NullString{String: string(data), Valid: true}
+	}
+	_, err := q.Exec(s.db.rebind(`INSERT INTO users (identifier, user_type, user_name, spotify_token) VALUES (?, ?, ?, ?)
+		ON CONFLICT (identifier) DO UPDATE SET user_type = excluded.user_type, user_name = excluded.user_name, spotify_token = excluded.spotify_token`),
+		key, string(info.UserType), info.UserName, token)
+	return err
+}
+
+func (s *UserStore) Delete(key string) error {
+	_, err := s.db.db.Exec(s.db.rebind("DELETE FROM users WHERE identifier = ?"), key)
+	return err
+}
+
+// getAll gets the objects for all keys from st
+func getAll[T any](st store.Store[T], keys []string) ([]*T, error) {
+	objs := make([]*T, 0, len(keys))
+	for _, key := range keys {
+		obj, err := st.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type MemorySet struct {
+	sync.RWMutex
+	members map[string]struct{}
+}
+
+func NewMemorySet() *MemorySet {
+	return &MemorySet{
+		members: make(map[string]struct{}),
+	}
+}
+
+func (s *MemorySet) GetAll() ([]string, error) {
+	s.RLock()
+	defer s.RUnlock()
+	members := make([]string, 0, len(s.members))
+	for member := range s.members {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (s *MemorySet) Add(key string) error {
+	s.Lock()
+	s.members[key] = struct{}{}
+	s.Unlock()
+	log.Trace("memory SADD for: ", key)
+	return nil
+}
+
+func (s *MemorySet) Has(key string) (bool, error) {
+	s.RLock()
+	_, ok := s.members[key]
+	s.RUnlock()
+	log.Trace("memory SISMEMBER for: ", key, " result: ", ok)
+	return ok, nil
+}
+
+func (s *MemorySet) Delete(key string) error {
+	s.Lock()
+	delete(s.members, key)
+	s.Unlock()
+	log.Trace("memory SREM for: ", key)
+	return nil
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MemoryStore keeps serialized objects in memory. Objects are serialized like in the RedisStore,
+// so callers never share the stored instance and behave the same with both backends.
+// Writes lock the key of the object, so a slow Update of one object doesn't block the others.
+type MemoryStore[T any] struct {
+	sync.RWMutex
+	objects map[string][]byte
+	locks   map[string]*keyLock
+}
+
+// keyLock serializes the writes of a key. It is removed, once no writer holds or waits for it.
+type keyLock struct {
+	sync.Mutex
+	refs int
+}
+
+func NewMemoryStore[T any]() *MemoryStore[T] {
+	return &MemoryStore[T]{
+		objects: make(map[string][]byte),
+		locks:   make(map[string]*keyLock),
+	}
+}
+
+// lock locks key for writing and returns the function unlocking it
+func (s *MemoryStore[T]) lock(key string) func() {
+	s.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &keyLock{}
+		s.locks[key] = l
+	}
+	l.refs++
+	s.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, key)
+		}
+		s.Unlock()
+	}
+}
+
+func (s *MemoryStore[T]) Get(key string) (*T, error) {
+	s.RLock()
+	data, ok := s.objects[key]
+	s.RUnlock()
+	log.Trace("memory GET for: ", key, " found: ", ok)
+	if !ok {
+		return nil, ErrObjNotFound
+	}
+	obj := new(T)
+	if err := deserialize(data, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *MemoryStore[T]) GetAll() ([]*T, error) {
+	s.RLock()
+	defer s.RUnlock()
+	objs := make([]*T, 0, len(s.objects))
+	for _, data := range s.objects {
+		obj := new(T)
+		if err := deserialize(data, obj); err != nil {
+			return nil, err
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+func (s *MemoryStore[T]) Save(obj *T, key string) error {
+	serialized, err := serialize(obj)
+	if err != nil {
+		return err
+	}
+	unlock := s.lock(key)
+	defer unlock()
+	s.Lock()
+	s.objects[key] = serialized
+	s.Unlock()
+	log.Trace("memory SET for: ", key)
+	return nil
+}
+
+// Update holds the lock of key while fn is applied, so updates of the same object are serialized
+func (s *MemoryStore[T]) Update(key string, fn func(obj *T) error) error {
+	unlock := s.lock(key)
+	defer unlock()
+	s.RLock()
+	data, ok := s.objects[key]
+	s.RUnlock()
+	if !ok {
+		return ErrObjNotFound
+	}
+	obj := new(T)
+	if err := deserialize(data, obj); err != nil {
+		return err
+	}
+	if err := fn(obj); err != nil {
+		return err
+	}
+	serialized, err := serialize(obj)
+	if err != nil {
+		return err
+	}
+	s.Lock()
+	s.objects[key] = serialized
+	s.Unlock()
+	log.Trace("memory UPDATE for: ", key)
+	return nil
+}
+
+func (s *MemoryStore[T]) Delete(key string) error {
+	unlock := s.lock(key)
+	defer unlock()
+	s.Lock()
+	delete(s.objects, key)
+	s.Unlock()
+	log.Trace("memory DEL for: ", key)
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"github.com/gomodule/redigo/redis"
 	pkgredis "github.com/jamfactoryapp/jamfactory-backend/internal/redis"
 	log "github.com/sirupsen/logrus"
+	"math/rand"
 	"strconv"
+	"time"
 )
 
 type RedisStore[T any] struct {
@@ -89,9 +91,74 @@ func (s RedisStore[T]) Save(obj *T, key string) error {
 	return err
 }
 
+// Update uses optimistic locking: the key is watched while fn is applied and the transaction is retried,
+// if the key was modified in the meantime
+func (s RedisStore[T]) Update(key string, fn func(obj *T) error) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+	redisKey := s.redisKey.Append(key).String()
+	for i := 0; i < maxUpdateRetries; i++ {
+		if _, err := conn.Do("WATCH", redisKey); err != nil {
+			return err
+		}
+		data, err := redis.Bytes(conn.Do("GET", redisKey))
+		if err != nil {
+			conn.Do("UNWATCH")
+			if err == redis.ErrNil {
+				return ErrObjNotFound
+			}
+			return err
+		}
+		obj := new(T)
+		if err := deserialize(data, obj); err != nil {
+			conn.Do("UNWATCH")
+			return err
+		}
+		if err := fn(obj); err != nil {
+			conn.Do("UNWATCH")
+			return err
+		}
+		serialized, err := serialize(obj)
+		if err != nil {
+			conn.Do("UNWATCH")
+			return err
+		}
+
+		if err := conn.Send("MULTI"); err != nil {
+			return err
+		}
+		if err := conn.Send("SET", redisKey, serialized); err != nil {
+			return err
+		}
+		reply, err := conn.Do("EXEC")
+		if err != nil {
+			return err
+		}
+		// EXEC replies nil, if the watched key was modified
+		if reply != nil {
+			log.Trace("redis UPDATE for: ", key, " after attempts: ", i+1)
+			return nil
+		}
+		if i < maxUpdateRetries-1 {
+			time.Sleep(updateWait(i))
+		}
+	}
+	log.Warn("redis UPDATE for: ", key, " failed after attempts: ", maxUpdateRetries)
+	return ErrConflict
+}
+
 func (s RedisStore[T]) Delete(key string) error {
 	conn := s.pool.Get()
 	_, err := conn.Do("DEL", s.redisKey.Append(key))
 	log.Trace("redis DO DEL for: ", key, " with err: ", err)
 	return err
 }
+
+// updateWait returns a random wait before the next attempt of an Update, which already failed attempts times
+func updateWait(attempts int) time.Duration {
+	limit := maxUpdateBackoff
+	if attempts < 10 && updateBackoff<<attempts < limit {
+		limit = updateBackoff << attempts
+	}
+	return time.Duration(rand.Int63n(int64(limit)))
+}
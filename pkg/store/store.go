@@ -4,11 +4,22 @@ import (
 	"bytes"
 	"encoding/gob"
 	"errors"
+	"time"
 )
 
 var (
 	ErrObjNotFound      = errors.New("store: obj not found")
 	ErrInterfaceConvert = errors.New("store: Failed to convert user from interface{} to []bytes")
+	ErrConflict         = errors.New("store: obj was modified concurrently")
+)
+
+const (
+	// maxUpdateRetries is the number of attempts of an optimistic Update before ErrConflict is returned
+	maxUpdateRetries = 20
+	// updateBackoff is the base of the random, exponentially growing wait between two attempts of an optimistic
+	// Update, so concurrent updates of the same object don't keep conflicting. The wait is capped at maxUpdateBackoff
+	updateBackoff    = time.Millisecond
+	maxUpdateBackoff = 500 * time.Millisecond
 )
 
 type Store[T any] interface {
@@ -16,6 +27,10 @@ type Store[T any] interface {
 	GetAll() ([]*T, error)
 	Save(obj *T, key string) error
 	Delete(key string) error
+	// Update atomically loads the object stored under key, applies fn and saves the result.
+	// fn may be called more than once and must only modify the passed object.
+	// If fn returns an error, nothing is saved and the error is returned.
+	Update(key string, fn func(obj *T) error) error
 }
 
 type Set interface {
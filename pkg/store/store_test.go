@@ -0,0 +1,198 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+)
+
+// concurrentUpdates is the number of updates racing for the same object
+const concurrentUpdates = 50
+
+type counter struct {
+	Value int
+}
+
+func newTestRedisPool(t *testing.T) *redis.Pool {
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	t.Cleanup(func() {
+		if err := pool.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return pool
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name  string
+		store func(t *testing.T) Store[counter]
+	}{
+		{"memory", func(t *testing.T) Store[counter] { return NewMemoryStore[counter]() }},
+		{"redis", func(t *testing.T) Store[counter] { return NewRedisStore[counter](newTestRedisPool(t), "counter") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.store(t)
+			if err := s.Save(&counter{}, "key"); err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, concurrentUpdates)
+			for i := 0; i < concurrentUpdates; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- s.Update("key", func(c *counter) error {
+						c.Value++
+						return nil
+					})
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c, err := s.Get("key")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Value != concurrentUpdates {
+				t.Errorf("value = %d after %d updates", c.Value, concurrentUpdates)
+			}
+
+			if err := s.Update("missing", func(c *counter) error { return nil }); err != ErrObjNotFound {
+				t.Errorf("Update of a missing object returned %v, want %v", err, ErrObjNotFound)
+			}
+		})
+	}
+}
+
+func TestRedisUpdateRetry(t *testing.T) {
+	s := NewRedisStore[counter](newTestRedisPool(t), "counter")
+	if err := s.Save(&counter{}, "key"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance modifies the object during the first attempt, so the update is applied again on top of it
+	attempts := 0
+	err := s.Update("key", func(c *counter) error {
+		attempts++
+		if attempts == 1 {
+			if err := s.Save(&counter{Value: 10}, "key"); err != nil {
+				return err
+			}
+		}
+		c.Value++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("update applied %d times, want 2", attempts)
+	}
+	c, err := s.Get("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Value != 11 {
+		t.Errorf("value = %d, want the concurrent write and the update", c.Value)
+	}
+
+	// An object, which is modified during every attempt, can't be updated
+	attempts = 0
+	err = s.Update("key", func(c *counter) error {
+		attempts++
+		return s.Save(&counter{Value: attempts}, "key")
+	})
+	if err != ErrConflict {
+		t.Errorf("Update returned %v, want %v", err, ErrConflict)
+	}
+	if attempts != maxUpdateRetries {
+		t.Errorf("update applied %d times, want %d", attempts, maxUpdateRetries)
+	}
+}
+
+func TestMemoryUpdateLocksKey(t *testing.T) {
+	s := NewMemoryStore[counter]()
+	for _, key := range []string{"slow", "other"} {
+		if err := s.Save(&counter{}, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- s.Update("slow", func(c *counter) error {
+			close(started)
+			<-release
+			c.Value++
+			return nil
+		})
+	}()
+	<-started
+
+	// Other objects can be updated, while the slow update runs
+	updated := make(chan error)
+	go func() {
+		updated <- s.Update("other", func(c *counter) error {
+			c.Value++
+			return nil
+		})
+	}()
+	select {
+	case err := <-updated:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the update of another object waited for the slow update")
+	}
+
+	// Writes of the same object wait for it
+	saved := make(chan error)
+	go func() {
+		saved <- s.Save(&counter{Value: 10}, "slow")
+	}()
+	select {
+	case <-saved:
+		t.Fatal("the object was saved during its update")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-saved; err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Get("slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Value != 10 {
+		t.Errorf("value = %d, want the save after the update", c.Value)
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.locks) != 0 {
+		t.Errorf("%d key locks left after all writes", len(s.locks))
+	}
+}
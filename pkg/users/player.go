@@ -3,49 +3,56 @@ package users
 import (
 	"context"
 	"errors"
-	"github.com/jamfactoryapp/jamfactory-backend/internal/utils"
-	"github.com/jamfactoryapp/jamfactory-backend/pkg/authenticator"
+	"sync"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	log "github.com/sirupsen/logrus"
-	"github.com/zmb3/spotify/v2"
 	"golang.org/x/oauth2"
-	"os"
 )
 
 var (
 	ErrDeviceNotActive = errors.New("device not active")
 )
 
+// player is used by HTTP handlers and the conductors concurrently. All state is guarded by the mutex.
+// enqueued contains the songs enqueued on the device. They become handedOver, once the device started playing them.
 type player struct {
-	CurrentSong   *spotify.FullTrack
-	Synchronized  bool
-	SyncCount     int
-	Active        bool
-	client        *spotify.Client
-	spotifyPlayer *spotify.PlayerState
+	sync.RWMutex
+	currentSong  *provider.Track
+	enqueued     []*provider.Track
+	handedOver   *provider.Track
+	synchronized bool
+	syncCount    int
+	active       bool
+	provider     provider.MusicProvider
+	clock        clock.Clock
+	playerState  *provider.PlayerState
+	observedAt   time.Time
 }
 
-func NewPlayer(ctx context.Context, authenticator *authenticator.Authenticator, token *oauth2.Token) player {
-	client := spotify.New(authenticator.Client(ctx, token))
-	playerState, err := client.PlayerState(ctx)
+func NewPlayer(ctx context.Context, providers provider.Factory, c clock.Clock, token *oauth2.Token) *player {
+	musicProvider := providers(ctx, token)
+	playerState, err := musicProvider.PlayerState(ctx)
 	if err != nil {
 		log.Warn(err)
+		playerState = &provider.PlayerState{}
 	}
-	return player{
-		CurrentSong:   nil,
-		Synchronized:  false,
-		SyncCount:     0,
-		Active:        false,
-		client:        client,
-		spotifyPlayer: playerState,
+	return &player{
+		provider:    musicProvider,
+		clock:       c,
+		playerState: playerState,
+		observedAt:  c.Now(),
 	}
 }
 
-func (p *player) Client() *spotify.Client {
-	return p.client
+func (p *player) Provider() provider.MusicProvider {
+	return p.provider
 }
 
 func (p *player) SetState(ctx context.Context, state bool) error {
-	playerState, err := p.Client().PlayerState(ctx)
+	playerState, err := p.Provider().PlayerState(ctx)
 	if err != nil {
 		return err
 	}
@@ -55,46 +62,78 @@ func (p *player) SetState(ctx context.Context, state bool) error {
 	}
 
 	if state {
-		err = p.Client().Play(ctx)
+		err = p.Provider().Resume(ctx)
 	} else {
-		err = p.Client().Pause(ctx)
+		err = p.Provider().Pause(ctx)
 	}
 
 	if err != nil {
 		return err
 	}
 
-	p.Synchronized = false
+	p.Lock()
+	p.synchronized = false
+	p.Unlock()
 	return nil
 }
 
-func (p *player) Play(ctx context.Context, track *spotify.FullTrack) error {
-	if !p.spotifyPlayer.Device.Active {
-		return ErrDeviceNotActive
-	}
+func (p *player) Play(ctx context.Context, track *provider.Track) error {
+	return p.PlayFrom(ctx, track, 0)
+}
 
-	playOptions := spotify.PlayOptions{
-		URIs: []spotify.URI{track.URI},
+// PlayFrom plays track starting at progress in milliseconds
+func (p *player) PlayFrom(ctx context.Context, track *provider.Track, progress int) error {
+	if !p.GetPlayerState().Device.Active {
+		return ErrDeviceNotActive
 	}
 
-	p.Synchronized = false
-	err := p.Client().PlayOpt(ctx, &playOptions)
+	p.Lock()
+	p.synchronized = false
+	p.Unlock()
+	err := p.Provider().PlayFrom(ctx, track, progress)
 	if err != nil {
 		return err
 	}
-	p.CurrentSong = track
+	p.Lock()
+	p.currentSong = track
+	p.Unlock()
+
+	return nil
+}
 
+// Enqueue hands track over to the device, which plays it right after the current song
+func (p *player) Enqueue(ctx context.Context, track *provider.Track) error {
+	if !p.GetPlayerState().Device.Active {
+		return ErrDeviceNotActive
+	}
+	if err := p.Provider().Enqueue(ctx, track); err != nil {
+		return err
+	}
+	p.Lock()
+	p.enqueued = append(p.enqueued, track)
+	p.Unlock()
 	return nil
 }
 
+// HandedOver returns the enqueued song once, after the device started playing it
+func (p *player) HandedOver() *provider.Track {
+	p.Lock()
+	defer p.Unlock()
+	track := p.handedOver
+	p.handedOver = nil
+	return track
+}
+
 func (p *player) SetDevice(ctx context.Context, id string) error {
-	playerState, err := p.Client().PlayerState(ctx)
+	playerState, err := p.Provider().PlayerState(ctx)
 	if err != nil {
 		return err
 	}
-	deviceID := spotify.ID(id)
-	if deviceID != playerState.Device.ID {
-		err := p.Client().TransferPlayback(ctx, deviceID, p.Active)
+	if id != playerState.Device.ID {
+		p.RLock()
+		active := p.active
+		p.RUnlock()
+		err := p.Provider().TransferPlayback(ctx, id, active)
 		if err != nil {
 			return err
 		}
@@ -102,66 +141,100 @@ func (p *player) SetDevice(ctx context.Context, id string) error {
 	return nil
 }
 
-func (p *player) Devices(ctx context.Context) ([]spotify.PlayerDevice, error) {
-	return p.Client().PlayerDevices(ctx)
+func (p *player) Devices(ctx context.Context) ([]provider.Device, error) {
+	return p.Provider().Devices(ctx)
 }
 
-func (p *player) GetPlayerState() *spotify.PlayerState {
-	return p.spotifyPlayer
+// GetPlayerState returns a copy of the last known PlayerState
+func (p *player) GetPlayerState() *provider.PlayerState {
+	p.RLock()
+	defer p.RUnlock()
+	state := *p.playerState
+	return &state
 }
 
-func (p *player) SetPlayerState(state *spotify.PlayerState) {
-	p.spotifyPlayer = state
+func (p *player) SetPlayerState(state *provider.PlayerState) {
+	p.Lock()
+	p.playerState = state
+	p.observedAt = p.clock.Now()
+	p.Unlock()
 }
 
-func (p *player) Playlists(ctx context.Context) (*spotify.SimplePlaylistPage, error) {
-	return p.Client().CurrentUsersPlaylists(ctx)
+// Remaining predicts the time left of the current song at now from the last observed progress and the time passed
+// since. ok is false, if nothing is playing.
+func (p *player) Remaining(now time.Time) (remaining time.Duration, ok bool) {
+	p.RLock()
+	defer p.RUnlock()
+	if !p.playerState.Playing || p.playerState.Item == nil {
+		return 0, false
+	}
+	progress := time.Duration(p.playerState.Progress)*time.Millisecond + now.Sub(p.observedAt)
+	return time.Duration(p.playerState.Item.Duration)*time.Millisecond - progress, true
 }
 
-func (p *player) Search(ctx context.Context, index string, searchType spotify.SearchType, options ...spotify.RequestOption) (interface{}, error) {
-	return p.Client().Search(ctx, index, searchType, options...)
+func (p *player) Synchronized() bool {
+	p.RLock()
+	defer p.RUnlock()
+	return p.synchronized
 }
 
-func (p *player) GetTrack(ctx context.Context, trackID string) (*spotify.FullTrack, error) {
-	return p.Client().GetTrack(ctx, spotify.ID(trackID))
+// Synchronize counts a successful PlayerState update and marks the player as synchronized
+func (p *player) Synchronize() {
+	p.Lock()
+	defer p.Unlock()
+	if p.synchronized {
+		return
+	}
+	p.syncCount++
+	if p.syncCount >= 1 {
+		p.synchronized = true
+		p.syncCount = 0
+	}
 }
 
-func (p *player) SetVolume(ctx context.Context, percent int) error {
-	err := p.Client().Volume(ctx, percent)
-	if err != nil {
-		return err
+// SongChanged reports whether the user started another song than the one played by JamFactory.
+// In this case the player is deactivated. Reaching the enqueued song is no change.
+func (p *player) SongChanged() bool {
+	p.Lock()
+	defer p.Unlock()
+	if !p.synchronized || p.playerState.Item == nil || p.currentSong == nil || p.playerState.Item.ID == p.currentSong.ID {
+		return false
+	}
+	for i, track := range p.enqueued {
+		if p.playerState.Item.ID == track.ID {
+			// The device plays its queue in order, so the songs enqueued before were played already
+			p.enqueued = p.enqueued[i+1:]
+			p.currentSong = track
+			p.handedOver = track
+			return false
+		}
 	}
-	return nil
+	p.active = false
+	p.currentSong = nil
+	p.enqueued = nil
+	return true
 }
 
-func (p *player) CreatePlaylist(ctx context.Context, name string, desc string, ids []spotify.ID) error {
-	user, err := p.Client().CurrentUser(ctx)
-	if err != nil {
-		return err
-	}
-	playlist, err := p.Client().CreatePlaylistForUser(ctx, user.ID, name, desc, false, false)
+func (p *player) Playlists(ctx context.Context) (*provider.Page[provider.Playlist], error) {
+	return p.Provider().Playlists(ctx)
+}
+
+func (p *player) Search(ctx context.Context, index string, searchType provider.SearchType) (*provider.SearchResult, error) {
+	return p.Provider().Search(ctx, index, searchType)
+}
+
+func (p *player) GetTrack(ctx context.Context, trackID string) (*provider.Track, error) {
+	return p.Provider().GetTrack(ctx, trackID)
+}
+
+func (p *player) SetVolume(ctx context.Context, percent int) error {
+	err := p.Provider().SetVolume(ctx, percent)
 	if err != nil {
 		return err
 	}
-	if utils.FileExists("./assets/playlist_cover.png") {
-		file, err := os.Open("./assets/playlist_cover.png")
-		defer utils.CloseProperly(file)
-		if err != nil {
-			return err
-		}
-		err = p.Client().SetPlaylistImage(ctx, playlist.ID, file)
-		if err != nil {
-			return err
-		}
-	}
-
-	idChunks := utils.SplitsIds(ids, 100)
-	for i := range idChunks {
-		_, err := p.Client().AddTracksToPlaylist(ctx, playlist.ID, idChunks[i]...)
-		if err != nil {
-			return err
-		}
-	}
 	return nil
+}
 
+func (p *player) CreatePlaylist(ctx context.Context, name string, desc string, ids []string) error {
+	return p.Provider().CreatePlaylist(ctx, name, desc, ids)
 }
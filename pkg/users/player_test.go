@@ -0,0 +1,62 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
+	"golang.org/x/oauth2"
+)
+
+var epoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestPlayer(c clock.Clock) *player {
+	return NewPlayer(context.Background(), provider.NewFakeFactory(c), c, &oauth2.Token{})
+}
+
+func TestRemaining(t *testing.T) {
+	c := clock.NewFake(epoch)
+	p := newTestPlayer(c)
+	track := &provider.Track{ID: "track", Duration: 180000}
+
+	if _, ok := p.Remaining(c.Now()); ok {
+		t.Error("Remaining reported a song, while nothing is playing")
+	}
+
+	p.SetPlayerState(&provider.PlayerState{Item: track, Progress: 60000, Playing: true})
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{"observed", 0, 120 * time.Second},
+		{"predicted", 90 * time.Second, 30 * time.Second},
+		{"overdue", 125 * time.Second, -5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, ok := p.Remaining(c.Now().Add(tt.elapsed))
+			if !ok {
+				t.Fatal("Remaining reported nothing playing")
+			}
+			if remaining != tt.want {
+				t.Errorf("Remaining() = %v, want %v", remaining, tt.want)
+			}
+		})
+	}
+
+	// The prediction starts from the time the state was observed, not from the time it was created
+	c.Advance(30 * time.Second)
+	p.SetPlayerState(&provider.PlayerState{Item: track, Progress: 90000, Playing: true})
+	c.Advance(10 * time.Second)
+	if remaining, _ := p.Remaining(c.Now()); remaining != 80*time.Second {
+		t.Errorf("Remaining() = %v after a new observation, want %v", remaining, 80*time.Second)
+	}
+
+	p.SetPlayerState(&provider.PlayerState{Item: track, Progress: 90000, Playing: false})
+	if _, ok := p.Remaining(c.Now()); ok {
+		t.Error("Remaining reported a paused song")
+	}
+}
@@ -3,7 +3,8 @@ package users
 import (
 	"context"
 	"github.com/jamfactoryapp/jamfactory-backend/api/errors"
-	"github.com/jamfactoryapp/jamfactory-backend/pkg/authenticator"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/clock"
+	"github.com/jamfactoryapp/jamfactory-backend/pkg/provider"
 	"github.com/jamfactoryapp/jamfactory-backend/pkg/store"
 	"golang.org/x/oauth2"
 )
@@ -28,10 +29,10 @@ type UserInformation struct {
 type User struct {
 	Identifier string
 	userInfo   store.Store[UserInformation]
-	player
+	*player
 }
 
-func New(ctx context.Context, identifier string, username string, usertype UserType, store store.Store[UserInformation], token *oauth2.Token, auth *authenticator.Authenticator) (*User, error) {
+func New(ctx context.Context, identifier string, username string, usertype UserType, store store.Store[UserInformation], token *oauth2.Token, providers provider.Factory, c clock.Clock) (*User, error) {
 	info := &UserInformation{
 		UserType:     usertype,
 		UserName:     username,
@@ -45,14 +46,14 @@ func New(ctx context.Context, identifier string, username string, usertype UserT
 	return &User{
 		Identifier: identifier,
 		userInfo:   store,
-		player:     NewPlayer(ctx, auth, token),
+		player:     NewPlayer(ctx, providers, c, token),
 	}, nil
 }
 
 func NewEmpty() *User {
 	return &User{
 		Identifier: "",
-		player:     player{},
+		player:     &player{clock: clock.New(), playerState: &provider.PlayerState{}},
 	}
 }
 
@@ -75,12 +76,12 @@ func (u *User) SetInfo(info *UserInformation) error {
 	}
 }
 
-func Load(ctx context.Context, identifier string, store store.Store[UserInformation], authenticator *authenticator.Authenticator) *User {
+func Load(ctx context.Context, identifier string, store store.Store[UserInformation], providers provider.Factory, c clock.Clock) *User {
 	info, _ := store.Get(identifier)
 	return &User{
 		Identifier: identifier,
 		userInfo:   store,
-		player:     NewPlayer(ctx, authenticator, info.SpotifyToken),
+		player:     NewPlayer(ctx, providers, c, info.SpotifyToken),
 	}
 }
 